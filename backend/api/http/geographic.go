@@ -0,0 +1,74 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+
+	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/httputils"
+)
+
+// Geographic endpoint - surfaces geographic request statistics without the full metrics blob
+func (s *APIService) getGeographic(w http.ResponseWriter, r *http.Request) {
+	geographicStats := s.Pool.GetGeographicStats()
+
+	var totalRequests uint64
+	for _, stats := range geographicStats {
+		totalRequests += stats.Requests
+	}
+
+	distribution := make([]map[string]interface{}, 0, len(geographicStats))
+	for _, stats := range geographicStats {
+		distribution = append(distribution, map[string]interface{}{
+			"country":     stats.Country,
+			"countryCode": stats.CountryCode,
+			"requests":    stats.Requests,
+			"percentage":  math.Round(stats.Percentage*10) / 10,
+			"lastSeen":    stats.LastSeen,
+		})
+	}
+
+	response := map[string]interface{}{
+		"countries":      distribution,
+		"totalCountries": len(geographicStats),
+		"totalRequests":  totalRequests,
+		"cacheSize":      s.Pool.GetGeographicCacheSize(),
+		"topCountry": func() string {
+			if len(geographicStats) > 0 {
+				return geographicStats[0].Country
+			}
+			return "Unknown"
+		}(),
+	}
+
+	httputils.RespondSuccessCtx(r.Context(), w, response, "Geographic statistics retrieved successfully")
+}
+
+// Geographic lookup endpoint - resolves geo data for a single IP, for debugging affinity/geo routing
+func (s *APIService) getGeographicLookup(w http.ResponseWriter, r *http.Request) {
+	ip := r.URL.Query().Get("ip")
+	if ip == "" {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("query parameter 'ip' is required"))
+		return
+	}
+	if net.ParseIP(ip) == nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("invalid IP address: %s", ip))
+		return
+	}
+
+	geoData := s.Pool.LookupGeographicData(ip)
+	if geoData == nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotFound, fmt.Errorf("geographic data not available for IP: %s", ip))
+		return
+	}
+
+	httputils.RespondSuccessCtx(r.Context(), w, geoData, "Geographic data retrieved successfully")
+}
+
+// Reset geographic statistics - admin-scoped, useful after testing or for periodic rollups
+func (s *APIService) resetGeographic(w http.ResponseWriter, r *http.Request) {
+	s.Pool.ResetGeographicStats()
+	s.Logger.Info("Geographic statistics reset")
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]string{"result": "geographic statistics reset"})
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/config"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
+)
+
+func TestAPIService_getGeographic(t *testing.T) {
+	// Create test dependencies
+	algo := &loadbalancing.RoundRobinAlgorithm{}
+	pool := loadbalancing.NewServerPoolWithLogger(algo, zap.NewNop())
+	cfg := &config.Config{APIKey: "test-key"}
+
+	service := &APIService{
+		Pool:   pool,
+		Config: cfg,
+		Logger: zap.NewNop(),
+	}
+
+	// Track a couple of requests from a private IP so the tracker records
+	// the "Local Network" stub without hitting the external geo API.
+	for i := 0; i < 3; i++ {
+		trackReq := httptest.NewRequest("GET", "/", nil)
+		trackReq.RemoteAddr = "10.0.0.1:5000"
+		pool.TrackRequestWithIP(trackReq)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/geographic", nil)
+	w := httptest.NewRecorder()
+
+	service.getGeographic(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp struct {
+		Data struct {
+			TotalCountries int    `json:"totalCountries"`
+			TotalRequests  uint64 `json:"totalRequests"`
+			TopCountry     string `json:"topCountry"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if resp.Data.TotalCountries != 1 {
+		t.Errorf("Expected 1 country, got %d", resp.Data.TotalCountries)
+	}
+	if resp.Data.TotalRequests != 3 {
+		t.Errorf("Expected 3 tracked requests, got %d", resp.Data.TotalRequests)
+	}
+	if resp.Data.TopCountry != "Local Network" {
+		t.Errorf("Expected top country 'Local Network', got %q", resp.Data.TopCountry)
+	}
+}
+
+func TestAPIService_getGeographicLookup(t *testing.T) {
+	// Stub the external geo API so the public-IP lookup stays local
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","country":"Germany","countryCode":"DE","regionName":"Berlin","city":"Berlin","lat":52.5,"lon":13.4,"timezone":"Europe/Berlin","isp":"Test ISP"}`))
+	}))
+	defer stub.Close()
+
+	pool := loadbalancing.NewServerPoolWithLogger(&loadbalancing.RoundRobinAlgorithm{}, zap.NewNop())
+	pool.GetGeographicTracker().SetAPIBaseURL(stub.URL)
+	service := &APIService{
+		Pool:   pool,
+		Config: &config.Config{APIKey: "test-key"},
+		Logger: zap.NewNop(),
+	}
+
+	lookup := func(ip string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest("GET", "/api/v1/geographic/lookup?ip="+ip, nil)
+		w := httptest.NewRecorder()
+		service.getGeographicLookup(w, req)
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp.Data
+	}
+
+	// Public IP resolves via the stubbed external API
+	w, data := lookup("8.8.8.8")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for public IP, got %d", w.Code)
+	}
+	if data["country"] != "Germany" {
+		t.Errorf("Expected country Germany, got %v", data["country"])
+	}
+
+	// Private IP returns the Local Network placeholder
+	w, data = lookup("192.168.1.1")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for private IP, got %d", w.Code)
+	}
+	if data["country"] != "Local Network" {
+		t.Errorf("Expected country 'Local Network', got %v", data["country"])
+	}
+
+	// Invalid IP is rejected
+	w, _ = lookup("not-an-ip")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid IP, got %d", w.Code)
+	}
+}
@@ -3,28 +3,37 @@ package http
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"os"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/config"
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/middleware"
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/routing"
+	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/diagnostics"
 	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/httputils"
+	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/logger"
 )
 
 // AddServerRequest is the payload for adding a backend server.
 type AddServerRequest struct {
-	ID     string `json:"id"`
-	URL    string `json:"url"`
-	Weight int    `json:"weight"`
+	ID            string            `json:"id"`
+	URL           string            `json:"url"`
+	Weight        int               `json:"weight"`
+	Tags          map[string]string `json:"tags,omitempty"`
+	ProxyProtocol string            `json:"proxyProtocol,omitempty"` // "v1" or "v2" to send a PROXY header to this backend
 }
 
 type UpdateServerRequest struct {
@@ -33,19 +42,68 @@ type UpdateServerRequest struct {
 }
 
 type ServerResponse struct {
-	ID                string `json:"id"`
-	URL               string `json:"url"`
-	Weight            int    `json:"weight"`
-	HealthStatus      string `json:"healthStatus"`
-	ActiveConnections int64  `json:"activeConnections"`
-	ResponseTime      int64  `json:"responseTime"` // Response time in milliseconds
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	Weight            int               `json:"weight"`
+	Tags              map[string]string `json:"tags,omitempty"`
+	HealthStatus      string            `json:"healthStatus"`
+	ActiveConnections int64             `json:"activeConnections"`
+	PeakConnections   int64             `json:"peakConnections"`   // Highest concurrent connection count observed
+	Draining          bool              `json:"draining"`          // True while the server drains connections at weight zero
+	ResponseTime      int64             `json:"responseTime"`      // Latest response time in milliseconds
+	ResponseTimeAvg   int64             `json:"responseTimeAvg"`   // Smoothed (EWMA) response time in milliseconds
+	ResponseTimeUs    int64             `json:"responseTimeUs"`    // Latest response time in microseconds, for sub-millisecond backends
+	ResponseTimeAvgUs int64             `json:"responseTimeAvgUs"` // Smoothed (EWMA) response time in microseconds
+}
+
+// MaintenanceSwitch is implemented by the load balancer handler so the API
+// can toggle global maintenance mode without importing core.
+type MaintenanceSwitch interface {
+	SetMaintenanceMode(on bool)
+	InMaintenanceMode() bool
 }
 
 // APIService provides handlers for the management API.
 type APIService struct {
-	Pool   *loadbalancing.ServerPool
-	Config *config.Config
-	Logger *zap.Logger
+	Pool        *loadbalancing.ServerPool
+	Config      *config.Config
+	Logger      *zap.Logger
+	Maintenance MaintenanceSwitch       // Optional; nil in standalone API deployments
+	IPFilter    *middleware.IPFilter    // Optional; nil when IP filtering is not wired in
+	Shedder     ShedRateReporter        // Optional; exposes the current load-shed rate in metrics
+	ConnMetrics ConnMetricsReporter     // Optional; exposes per-server connection lifecycle counters
+	Sessions    SessionCountsReporter   // Optional; exposes per-backend sticky session counts
+	Router      *routing.L7Router       // Optional; nil when L7 routing is not wired in
+	APIKeys     *middleware.APIKeyStore // Optional; enables runtime API key rotation
+
+	// Diagnostics are expensive to assemble and the endpoint is public, so
+	// results are cached briefly rather than rebuilt per request.
+	diagMu       sync.Mutex
+	diagCached   *diagnostics.DiagnosticInfo
+	diagCachedAt time.Time
+}
+
+// diagnosticsCacheTTL is how long a diagnostics result is served from cache.
+// Short enough to stay useful for debugging, long enough that rapid-fire
+// requests to the public endpoint can't make the process busy-loop.
+const diagnosticsCacheTTL = 5 * time.Second
+
+// ShedRateReporter is implemented by the load balancer when load shedding is
+// enabled.
+type ShedRateReporter interface {
+	ShedRate() float64
+}
+
+// ConnMetricsReporter is implemented by the server manager; it reports TCP
+// connection lifecycle counters (new/active/idle/closed) per managed server.
+type ConnMetricsReporter interface {
+	ConnectionMetrics() map[string]map[string]int64
+}
+
+// SessionCountsReporter is implemented by the session manager; it reports how
+// many sticky sessions are pinned to each backend.
+type SessionCountsReporter interface {
+	SessionCounts() map[string]uint64
 }
 
 func (s *APIService) RegisterRoutes(router *mux.Router) {
@@ -57,20 +115,42 @@ func (s *APIService) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/v1/ping", s.ping).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/v1/diagnostics", s.diagnostics).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/v1/metrics", s.getMetrics).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/v1/geographic", s.getGeographic).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/v1/geographic", s.resetGeographic).Methods("DELETE")
+	router.HandleFunc("/api/v1/geographic/lookup", s.getGeographicLookup).Methods("GET", "OPTIONS")
+
+	router.HandleFunc("/api/v1/state/export", s.exportState).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/v1/state/import", s.importState).Methods("POST", "OPTIONS")
+
+	router.HandleFunc("/api/v1/maintenance-mode", s.getMaintenanceMode).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/v1/maintenance-mode", s.setMaintenanceMode).Methods("PUT", "OPTIONS")
+
+	router.HandleFunc("/api/v1/maintenance", s.setMaintenanceByTag).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/v1/maintenance", s.clearMaintenanceByTag).Methods("DELETE")
 
 	router.HandleFunc("/api/v1/servers", s.listServers).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/v1/servers", s.addServer).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/v1/servers/{id}/connections", s.getServerConnections).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/v1/servers/{id}/history", s.getServerHistory).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/v1/servers/{id}/weight", s.setServerWeight).Methods("PATCH", "OPTIONS")
 	router.HandleFunc("/api/v1/servers/{id}", s.updateServer).Methods("PUT", "OPTIONS")
 	router.HandleFunc("/api/v1/servers/{id}", s.deleteServer).Methods("DELETE", "OPTIONS")
 	router.HandleFunc("/api/v1/config", s.getConfig).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/v1/config", s.updateConfig).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/v1/config/validate", s.validateConfig).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/v1/config/apikey", s.rotateAPIKey).Methods("POST", "OPTIONS")
 	// Advanced features
+	router.HandleFunc("/api/v1/algorithms", s.listAlgorithms).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/v1/sessions", s.listSessions).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/v1/config/algorithm", s.getAlgorithm).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/v1/config/algorithm", s.setAlgorithm).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/v1/config/loglevel", s.getLogLevel).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/v1/config/loglevel", s.setLogLevel).Methods("PUT", "OPTIONS")
 	router.HandleFunc("/api/v1/session-settings", s.getSessionSettings).Methods("GET")
 	router.HandleFunc("/api/v1/session-settings", s.updateSessionSettings).Methods("PUT")
 	router.HandleFunc("/api/v1/certs/upload", s.uploadCerts).Methods("POST")
 	router.HandleFunc("/api/v1/routing-rules", s.listRoutingRules).Methods("GET")
+	router.HandleFunc("/api/v1/routing-rules/test", s.testRoutingRule).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/v1/routing-rules", s.addRoutingRule).Methods("POST")
 	router.HandleFunc("/api/v1/routing-rules/{id}", s.updateRoutingRule).Methods("PUT")
 	router.HandleFunc("/api/v1/routing-rules/{id}", s.deleteRoutingRule).Methods("DELETE")
@@ -80,6 +160,8 @@ func (s *APIService) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/v1/rate-limits/{id}", s.deleteRateLimit).Methods("DELETE")
 	router.HandleFunc("/api/v1/ip-filters", s.listIPFilters).Methods("GET")
 	router.HandleFunc("/api/v1/ip-filters", s.addIPFilter).Methods("POST")
+	router.HandleFunc("/api/v1/ip-filters/reorder", s.reorderIPFilters).Methods("POST")
+	router.HandleFunc("/api/v1/ip-filters/policy", s.setIPFilterPolicy).Methods("PUT")
 	router.HandleFunc("/api/v1/ip-filters/{id}", s.updateIPFilter).Methods("PUT")
 	router.HandleFunc("/api/v1/ip-filters/{id}", s.deleteIPFilter).Methods("DELETE")
 	router.HandleFunc("/api/v1/waf-rules", s.listWAFRules).Methods("GET")
@@ -88,20 +170,99 @@ func (s *APIService) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/v1/waf-rules/{id}", s.deleteWAFRule).Methods("DELETE")
 }
 
+// listServers returns all backend servers. An optional ?tag=key or
+// ?tag=key=value query filters the list to servers carrying that tag.
 func (s *APIService) listServers(w http.ResponseWriter, r *http.Request) {
-	servers := s.Pool.GetServers()
+	var servers []*model.BackendServer
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		key, value, _ := strings.Cut(tag, "=")
+		servers = s.Pool.GetServersByTag(key, value)
+	} else {
+		servers = s.Pool.GetServers()
+	}
 	resp := make([]ServerResponse, 0, len(servers))
 	for _, srv := range servers {
 		resp = append(resp, ServerResponse{
 			ID:                srv.ID,
 			URL:               srv.URL.String(),
 			Weight:            srv.Weight,
+			Tags:              srv.Tags,
 			HealthStatus:      string(srv.HealthStatus),
 			ActiveConnections: srv.GetActiveConnections(),
+			PeakConnections:   srv.GetPeakConnections(),
+			Draining:          srv.IsDraining(),
 			ResponseTime:      srv.GetResponseTime(), // Add real response time
+			ResponseTimeAvg:   srv.GetResponseTimeEWMA(),
+			ResponseTimeUs:    srv.GetResponseTimeMicros(),
+			ResponseTimeAvgUs: srv.GetResponseTimeEWMAMicros(),
 		})
 	}
-	httputils.RespondJSON(w, http.StatusOK, resp)
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, resp)
+}
+
+func (s *APIService) getMaintenanceMode(w http.ResponseWriter, r *http.Request) {
+	if s.Maintenance == nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotImplemented, fmt.Errorf("maintenance mode is not available in this deployment"))
+		return
+	}
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]bool{"enabled": s.Maintenance.InMaintenanceMode()})
+}
+
+func (s *APIService) setMaintenanceMode(w http.ResponseWriter, r *http.Request) {
+	if s.Maintenance == nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotImplemented, fmt.Errorf("maintenance mode is not available in this deployment"))
+		return
+	}
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	s.Maintenance.SetMaintenanceMode(req.Enabled)
+	s.Logger.Info("Global maintenance mode changed", zap.Bool("enabled", req.Enabled))
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]bool{"enabled": req.Enabled})
+}
+
+// setMaintenanceByTag puts every server carrying the ?tag= label into
+// MAINTENANCE for rolling deploys, returning the affected server IDs.
+func (s *APIService) setMaintenanceByTag(w http.ResponseWriter, r *http.Request) {
+	s.bulkMaintenance(w, r, model.MAINTENANCE)
+}
+
+// clearMaintenanceByTag takes every matching server back out of MAINTENANCE.
+// Servers return as UNHEALTHY until the next health check promotes them.
+func (s *APIService) clearMaintenanceByTag(w http.ResponseWriter, r *http.Request) {
+	s.bulkMaintenance(w, r, model.UNHEALTHY)
+}
+
+func (s *APIService) bulkMaintenance(w http.ResponseWriter, r *http.Request, status model.HealthStatus) {
+	tag := r.URL.Query().Get("tag")
+	if tag == "" {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("tag query parameter is required"))
+		return
+	}
+	key, value, _ := strings.Cut(tag, "=")
+
+	affected := make([]string, 0)
+	for _, srv := range s.Pool.GetServersByTag(key, value) {
+		if status == model.UNHEALTHY && srv.HealthStatus != model.MAINTENANCE {
+			continue // Only clear servers that are actually in maintenance
+		}
+		s.Pool.SetBackendStatus(srv.ID, status)
+		affected = append(affected, srv.ID)
+	}
+
+	s.Logger.Info("Bulk maintenance update",
+		zap.String("tag", tag),
+		zap.String("status", string(status)),
+		zap.Strings("affected", affected))
+	httputils.RespondSuccessCtx(r.Context(), w, map[string]interface{}{
+		"tag":      tag,
+		"status":   string(status),
+		"affected": affected,
+	}, "Maintenance status updated")
 }
 
 func (s *APIService) addServer(w http.ResponseWriter, r *http.Request) {
@@ -164,8 +325,23 @@ func (s *APIService) addServer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.Pool.AddServer(server)
+	server.Tags = req.Tags
+	if err := model.ValidateProxyProtocolVersion(req.ProxyProtocol); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	server.ProxyProtocol = req.ProxyProtocol
+	if err := s.Pool.TryAddServer(server); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusConflict, err)
+		return
+	}
 	s.Logger.Info("Added new backend server", zap.String("id", server.ID), zap.String("url", server.URL.String()))
+	config.RuntimeRLock()
+	warmUp := s.Config.WarmUpConnections
+	config.RuntimeRUnlock()
+	if warmUp > 0 {
+		go loadbalancing.WarmUpBackend(server, warmUp, s.Logger)
+	}
 
 	response := ServerResponse{
 		ID:                server.ID,
@@ -173,10 +349,90 @@ func (s *APIService) addServer(w http.ResponseWriter, r *http.Request) {
 		Weight:            server.Weight,
 		HealthStatus:      string(server.HealthStatus),
 		ActiveConnections: server.GetActiveConnections(),
+		Draining:          server.IsDraining(),
 		ResponseTime:      server.GetResponseTime(),
+		ResponseTimeUs:    server.GetResponseTimeMicros(),
 	}
 
-	httputils.RespondCreated(w, response, "Backend server added successfully")
+	httputils.RespondCreatedCtx(r.Context(), w, response, "Backend server added successfully")
+}
+
+// getServerHistory returns a backend's health status transitions, oldest
+// first, for post-incident timelines.
+func (s *APIService) getServerHistory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	for _, srv := range s.Pool.GetServers() {
+		if srv.ID == id {
+			httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]interface{}{
+				"id":      srv.ID,
+				"history": srv.GetHealthHistory(),
+			})
+			return
+		}
+	}
+	httputils.RespondErrorCtx(r.Context(), w, http.StatusNotFound, errServerNotFound(id))
+}
+
+func (s *APIService) getServerConnections(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	for _, srv := range s.Pool.GetServers() {
+		if srv.ID == id {
+			httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]interface{}{
+				"id":                srv.ID,
+				"activeConnections": srv.GetActiveConnections(),
+				"peakConnections":   srv.GetPeakConnections(),
+				"totalRequests":     srv.GetTotalRequests(),
+			})
+			return
+		}
+	}
+	httputils.RespondErrorCtx(r.Context(), w, http.StatusNotFound, errServerNotFound(id))
+}
+
+// setServerWeight applies a quick weight-only change to one server, resetting
+// weighted-algorithm state so the new distribution takes effect immediately.
+func (s *APIService) setServerWeight(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var req struct {
+		Weight *int `json:"weight"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Weight == nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("weight is required"))
+		return
+	}
+	if *req.Weight < 0 {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("weight must not be negative, got %d", *req.Weight))
+		return
+	}
+	var found *model.BackendServer
+	for _, srv := range s.Pool.GetServers() {
+		if srv.ID == id {
+			found = srv
+			break
+		}
+	}
+	if found == nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotFound, errServerNotFound(id))
+		return
+	}
+	found.Weight = *req.Weight
+	s.Pool.ResetAlgorithmState()
+	s.Logger.Info("Updated backend server weight", zap.String("id", found.ID), zap.Int("weight", found.Weight))
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, ServerResponse{
+		ID:                found.ID,
+		URL:               found.URL.String(),
+		Weight:            found.Weight,
+		HealthStatus:      string(found.HealthStatus),
+		ActiveConnections: found.GetActiveConnections(),
+		Draining:          found.IsDraining(),
+		ResponseTime:      found.GetResponseTime(),
+		ResponseTimeUs:    found.GetResponseTimeMicros(),
+		ResponseTimeAvgUs: found.GetResponseTimeEWMAMicros(),
+	})
 }
 
 func (s *APIService) updateServer(w http.ResponseWriter, r *http.Request) {
@@ -184,7 +440,7 @@ func (s *APIService) updateServer(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 	var req UpdateServerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		httputils.RespondError(w, http.StatusBadRequest, err)
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
 		return
 	}
 	servers := s.Pool.GetServers()
@@ -196,23 +452,26 @@ func (s *APIService) updateServer(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if found == nil {
-		httputils.RespondError(w, http.StatusNotFound, errServerNotFound(id))
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotFound, errServerNotFound(id))
 		return
 	}
 	if req.Weight != nil {
 		found.Weight = *req.Weight
 	}
 	if req.HealthStatus != nil {
-		found.SetStatus(*req.HealthStatus)
+		s.Pool.SetBackendStatus(found.ID, *req.HealthStatus)
 	}
 	s.Logger.Info("Updated backend server", zap.String("id", found.ID))
-	httputils.RespondJSON(w, http.StatusOK, ServerResponse{
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, ServerResponse{
 		ID:                found.ID,
 		URL:               found.URL.String(),
 		Weight:            found.Weight,
 		HealthStatus:      string(found.HealthStatus),
 		ActiveConnections: found.GetActiveConnections(),
+		Draining:          found.IsDraining(),
 		ResponseTime:      found.GetResponseTime(),
+		ResponseTimeUs:    found.GetResponseTimeMicros(),
+		ResponseTimeAvgUs: found.GetResponseTimeEWMAMicros(),
 	})
 }
 
@@ -221,41 +480,234 @@ func (s *APIService) deleteServer(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 	removed := s.Pool.RemoveServer(id)
 	if !removed {
-		httputils.RespondError(w, http.StatusNotFound, errServerNotFound(id))
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotFound, errServerNotFound(id))
 		return
 	}
 	s.Logger.Info("Removed backend server", zap.String("id", id))
-	httputils.RespondJSON(w, http.StatusOK, map[string]string{"result": "deleted"})
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]string{"result": "deleted"})
 }
 
 func (s *APIService) getConfig(w http.ResponseWriter, r *http.Request) {
-	httputils.RespondJSON(w, http.StatusOK, s.Config)
+	// Hold the read lock across serialization so a concurrent SIGHUP reload
+	// can't rewrite the struct mid-marshal.
+	config.RuntimeRLock()
+	defer config.RuntimeRUnlock()
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, s.Config)
 }
 
+// updateConfig applies runtime-safe config changes. Port changes are rejected
+// with 409 Conflict: the listeners are bound at startup and a silent in-memory
+// update would be misleading, so changing ports requires a config file edit
+// and a restart.
 func (s *APIService) updateConfig(w http.ResponseWriter, r *http.Request) {
-	// For demo: only allow updating LoadBalancerPort and ApiPort
 	var req struct {
 		LoadBalancerPort *int `json:"loadBalancerPort,omitempty"`
 		ApiPort          *int `json:"apiPort,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		httputils.RespondError(w, http.StatusBadRequest, err)
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
 		return
 	}
-	if req.LoadBalancerPort != nil {
-		s.Config.LoadBalancerPort = *req.LoadBalancerPort
+	config.RuntimeRLock()
+	lbPort, apiPort := s.Config.LoadBalancerPort, s.Config.ApiPort
+	config.RuntimeRUnlock()
+	if req.LoadBalancerPort != nil && *req.LoadBalancerPort != lbPort {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusConflict,
+			fmt.Errorf("loadBalancerPort cannot be changed at runtime; update the config file and restart the service"))
+		return
 	}
-	if req.ApiPort != nil {
-		s.Config.ApiPort = *req.ApiPort
+	if req.ApiPort != nil && *req.ApiPort != apiPort {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusConflict,
+			fmt.Errorf("apiPort cannot be changed at runtime; update the config file and restart the service"))
+		return
 	}
-	s.Logger.Info("Updated config", zap.Any("config", s.Config))
-	httputils.RespondJSON(w, http.StatusOK, s.Config)
+	s.Logger.Info("Updated config",
+		zap.Int("loadBalancerPort", lbPort),
+		zap.Int("apiPort", apiPort))
+	config.RuntimeRLock()
+	defer config.RuntimeRUnlock()
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, s.Config)
+}
+
+// validateConfig dry-runs a candidate config (YAML or JSON body) against
+// Config.Validate without touching the live configuration.
+func (s *APIService) validateConfig(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+
+	// YAML is a superset of JSON, so one decoder handles both formats
+	var candidate config.Config
+	if err := yaml.Unmarshal(body, &candidate); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("failed to parse candidate config: %w", err))
+		return
+	}
+
+	problems := candidate.Validate()
+	if len(problems) > 0 {
+		httputils.RespondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"valid":    false,
+			"problems": problems,
+		})
+		return
+	}
+
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]interface{}{
+		"valid":  true,
+		"config": candidate,
+	})
 }
 
 // --- Advanced feature handler stubs ---
+// algorithmByName builds a load balancing algorithm from its config name,
+// applying configured adaptive scoring weights where relevant.
+func (s *APIService) algorithmByName(name string) (loadbalancing.LoadBalancingAlgorithm, error) {
+	switch name {
+	case "roundrobin":
+		return &loadbalancing.RoundRobinAlgorithm{}, nil
+	case "leastconnections":
+		return &loadbalancing.LeastConnectionsAlgorithm{}, nil
+	case "iphash":
+		return &loadbalancing.IPHashAlgorithm{}, nil
+	case "weighted":
+		return loadbalancing.NewWeightedRoundRobinAlgorithm(), nil
+	case "weightedrandom":
+		return &loadbalancing.WeightedAlgorithm{}, nil
+	case "adaptive":
+		adaptive := loadbalancing.NewAdaptiveAlgorithm()
+		config.RuntimeRLock()
+		scoring := s.Config.AdaptiveScoring
+		config.RuntimeRUnlock()
+		if scoring.ConnectionWeight > 0 {
+			adaptive.ConnectionWeight = scoring.ConnectionWeight
+		}
+		if scoring.LatencyWeight > 0 {
+			adaptive.LatencyWeight = scoring.LatencyWeight
+		}
+		return adaptive, nil
+	}
+	return nil, fmt.Errorf("unknown algorithm: %s. Supported algorithms: roundrobin, leastconnections, iphash, weighted, weightedrandom, adaptive", name)
+}
+
+// AlgorithmInfo describes a load balancing algorithm and its capabilities, so
+// the dashboard can render the right controls per algorithm.
+type AlgorithmInfo struct {
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	SupportsWeights  bool   `json:"supportsWeights"`  // Server weights influence selection
+	SupportsAffinity bool   `json:"supportsAffinity"` // Same client tends to reach the same backend
+	Stateful         bool   `json:"stateful"`         // Keeps selection state across requests
+}
+
+// algorithmCatalog lists every supported algorithm with its capabilities, in
+// the order the dashboard should present them.
+func algorithmCatalog() []AlgorithmInfo {
+	return []AlgorithmInfo{
+		{
+			Name:        "roundrobin",
+			Description: "Distributes requests evenly across all servers in sequence",
+			Stateful:    true, // Keeps a rotation counter
+		},
+		{
+			Name:        "leastconnections",
+			Description: "Routes requests to the server with the fewest active connections",
+		},
+		{
+			Name:             "iphash",
+			Description:      "Routes requests based on client IP hash for session persistence",
+			SupportsAffinity: true,
+		},
+		{
+			Name:            "weighted",
+			Description:     "Distributes requests based on server weights using weighted round robin",
+			SupportsWeights: true,
+			Stateful:        true, // Keeps smooth weighted round robin state per server
+		},
+		{
+			Name:            "weightedrandom",
+			Description:     "Distributes requests based on server weights using random selection",
+			SupportsWeights: true,
+		},
+		{
+			Name:        "adaptive",
+			Description: "Routes requests by scoring live connection counts and response times",
+		},
+	}
+}
+
+// listAlgorithms reports every supported algorithm with its capability flags
+// alongside the currently active one.
+func (s *APIService) listAlgorithms(w http.ResponseWriter, r *http.Request) {
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]interface{}{
+		"current":    s.currentAlgorithm(),
+		"algorithms": algorithmCatalog(),
+	})
+}
+
+// RotateAPIKeyRequest carries the replacement API key and how long the old
+// key stays valid after the switch.
+type RotateAPIKeyRequest struct {
+	APIKey       string `json:"apiKey"`
+	GraceSeconds int    `json:"graceSeconds"` // 0 revokes the old key immediately
+}
+
+// rotateAPIKey replaces the API key at runtime. Reaching this handler already
+// required the current key, so rotation is admin-scoped by construction. The
+// old key keeps working for the requested grace period to avoid locking out
+// clients mid-rotation.
+func (s *APIService) rotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if s.APIKeys == nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotImplemented, fmt.Errorf("runtime key rotation is not available in this deployment"))
+		return
+	}
+	var req RotateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.APIKey) < 16 {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("new API key must be at least 16 characters"))
+		return
+	}
+	if req.GraceSeconds < 0 {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("graceSeconds must not be negative"))
+		return
+	}
+
+	grace := time.Duration(req.GraceSeconds) * time.Second
+	s.APIKeys.Rotate(req.APIKey, grace)
+	config.RuntimeLock()
+	s.Config.APIKey = req.APIKey
+	config.RuntimeUnlock()
+	s.Logger.Info("API key rotated", zap.Int("graceSeconds", req.GraceSeconds))
+	httputils.RespondSuccessCtx(r.Context(), w, map[string]interface{}{
+		"graceSeconds": req.GraceSeconds,
+	}, "API key rotated")
+}
+
+// listSessions reports how many sticky sessions are pinned to each backend,
+// for debugging affinity skew. Counts grow on each pin and are approximate.
+func (s *APIService) listSessions(w http.ResponseWriter, r *http.Request) {
+	if s.Sessions == nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotImplemented, fmt.Errorf("session tracking is not available in this deployment"))
+		return
+	}
+	counts := s.Sessions.SessionCounts()
+	var total uint64
+	for _, n := range counts {
+		total += n
+	}
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]interface{}{
+		"sessions": counts,
+		"total":    total,
+	})
+}
+
 func (s *APIService) getAlgorithm(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
-		"algorithm": s.Config.LoadBalancingAlgorithm,
+		"algorithm": s.currentAlgorithm(),
 		"supportedAlgorithms": []string{
 			"roundrobin",
 			"leastconnections",
@@ -271,7 +723,15 @@ func (s *APIService) getAlgorithm(w http.ResponseWriter, r *http.Request) {
 			"weightedrandom":   "Distributes requests based on server weights using random selection",
 		},
 	}
-	httputils.RespondJSON(w, http.StatusOK, response)
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, response)
+}
+
+// currentAlgorithm reads the active algorithm name under the config read
+// lock, since setAlgorithm and SIGHUP reloads can change it concurrently.
+func (s *APIService) currentAlgorithm() string {
+	config.RuntimeRLock()
+	defer config.RuntimeRUnlock()
+	return s.Config.LoadBalancingAlgorithm
 }
 
 func (s *APIService) setAlgorithm(w http.ResponseWriter, r *http.Request) {
@@ -280,30 +740,41 @@ func (s *APIService) setAlgorithm(w http.ResponseWriter, r *http.Request) {
 	}
 	var req reqBody
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		httputils.RespondError(w, http.StatusBadRequest, err)
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
 		return
 	}
-	var algo loadbalancing.LoadBalancingAlgorithm
-	switch req.Algorithm {
-	case "roundrobin":
-		algo = &loadbalancing.RoundRobinAlgorithm{}
-	case "leastconnections":
-		algo = &loadbalancing.LeastConnectionsAlgorithm{}
-	case "iphash":
-		algo = &loadbalancing.IPHashAlgorithm{}
-	case "weighted":
-		algo = loadbalancing.NewWeightedRoundRobinAlgorithm()
-	case "weightedrandom":
-		algo = &loadbalancing.WeightedAlgorithm{}
-	default:
-		httputils.RespondError(w, http.StatusBadRequest, fmt.Errorf("unknown algorithm: %s. Supported algorithms: roundrobin, leastconnections, iphash, weighted, weightedrandom", req.Algorithm))
+	algo, err := s.algorithmByName(req.Algorithm)
+	if err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
 		return
 	}
 	s.Pool.SetAlgorithm(algo)
+	config.RuntimeLock()
 	s.Config.LoadBalancingAlgorithm = req.Algorithm
+	config.RuntimeUnlock()
 	s.Logger.Info("Changed load balancing algorithm", zap.String("algorithm", req.Algorithm))
-	httputils.RespondJSON(w, http.StatusOK, map[string]string{"result": "algorithm updated", "algorithm": req.Algorithm})
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]string{"result": "algorithm updated", "algorithm": req.Algorithm})
+}
+func (s *APIService) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]string{"level": logger.GetLevel()})
+}
+
+func (s *APIService) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	if err := logger.SetLevel(req.Level); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	s.Logger.Info("Changed log level", zap.String("level", req.Level))
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]string{"result": "log level updated", "level": logger.GetLevel()})
 }
+
 func (s *APIService) getSessionSettings(w http.ResponseWriter, r *http.Request) {
 	// TODO: Return current session settings
 	httputils.RespondJSON(w, http.StatusNotImplemented, map[string]string{"message": "getSessionSettings not implemented"})
@@ -316,6 +787,59 @@ func (s *APIService) uploadCerts(w http.ResponseWriter, r *http.Request) {
 	// TODO: Accept multipart/form-data, save cert/key files, reload TLS config
 	httputils.RespondJSON(w, http.StatusNotImplemented, map[string]string{"message": "uploadCerts not implemented"})
 }
+
+// RoutingTestRequest describes a synthetic request to evaluate against the
+// routing rules without sending any live traffic.
+type RoutingTestRequest struct {
+	Host    string            `json:"host"`
+	Path    string            `json:"path"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// testRoutingRule dry-runs the L7 routing rules against a sample request and
+// reports which rule matched and the pool it resolves to.
+func (s *APIService) testRoutingRule(w http.ResponseWriter, r *http.Request) {
+	if s.Router == nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotImplemented, fmt.Errorf("L7 routing is not available in this deployment"))
+		return
+	}
+	var req RoutingTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	if req.Path == "" {
+		req.Path = "/"
+	}
+
+	sample, err := http.NewRequest(req.Method, "http://"+req.Host+req.Path, nil)
+	if err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	sample.Host = req.Host
+	for name, value := range req.Headers {
+		sample.Header.Set(name, value)
+	}
+
+	_, rule := s.Router.RouteRule(sample)
+	if rule == nil {
+		httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]interface{}{
+			"matched": false,
+		})
+		return
+	}
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]interface{}{
+		"matched":      true,
+		"ruleId":       rule.ID,
+		"targetPoolId": rule.TargetPoolID,
+	})
+}
+
 func (s *APIService) listRoutingRules(w http.ResponseWriter, r *http.Request) {
 	// TODO: List routing rules
 	httputils.RespondJSON(w, http.StatusNotImplemented, map[string]string{"message": "listRoutingRules not implemented"})
@@ -348,21 +872,115 @@ func (s *APIService) deleteRateLimit(w http.ResponseWriter, r *http.Request) {
 	// TODO: Delete rate limit
 	httputils.RespondJSON(w, http.StatusNotImplemented, map[string]string{"message": "deleteRateLimit not implemented"})
 }
+
+// requireIPFilter guards the IP-filter handlers in deployments without one.
+func (s *APIService) requireIPFilter(w http.ResponseWriter, r *http.Request) bool {
+	if s.IPFilter == nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotImplemented, fmt.Errorf("IP filtering is not available in this deployment"))
+		return false
+	}
+	return true
+}
+
+// listIPFilters returns the rules in evaluation order plus the default policy.
 func (s *APIService) listIPFilters(w http.ResponseWriter, r *http.Request) {
-	// TODO: List IP filters
-	httputils.RespondJSON(w, http.StatusNotImplemented, map[string]string{"message": "listIPFilters not implemented"})
+	if !s.requireIPFilter(w, r) {
+		return
+	}
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]interface{}{
+		"defaultPolicy": ipFilterPolicyName(s.IPFilter.DefaultAllow()),
+		"rules":         s.IPFilter.Rules(),
+	})
 }
+
 func (s *APIService) addIPFilter(w http.ResponseWriter, r *http.Request) {
-	// TODO: Add IP filter
-	httputils.RespondJSON(w, http.StatusNotImplemented, map[string]string{"message": "addIPFilter not implemented"})
+	if !s.requireIPFilter(w, r) {
+		return
+	}
+	var req struct {
+		CIDR   string `json:"cidr"`
+		Action string `json:"action"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	rule, err := s.IPFilter.AddRule(req.CIDR, middleware.IPFilterAction(req.Action))
+	if err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	s.Logger.Info("Added IP filter rule", zap.String("id", rule.ID), zap.String("cidr", rule.CIDR), zap.String("action", string(rule.Action)))
+	httputils.RespondCreatedCtx(r.Context(), w, rule, "IP filter rule added")
+}
+
+// reorderIPFilters rearranges the evaluation order; the body must list every
+// current rule ID exactly once.
+func (s *APIService) reorderIPFilters(w http.ResponseWriter, r *http.Request) {
+	if !s.requireIPFilter(w, r) {
+		return
+	}
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.IPFilter.Reorder(req.IDs); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, s.IPFilter.Rules())
+}
+
+func (s *APIService) setIPFilterPolicy(w http.ResponseWriter, r *http.Request) {
+	if !s.requireIPFilter(w, r) {
+		return
+	}
+	var req struct {
+		DefaultPolicy string `json:"defaultPolicy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	switch req.DefaultPolicy {
+	case "allow":
+		s.IPFilter.SetDefaultPolicy(true)
+	case "deny":
+		s.IPFilter.SetDefaultPolicy(false)
+	default:
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("defaultPolicy must be \"allow\" or \"deny\""))
+		return
+	}
+	s.Logger.Info("Changed IP filter default policy", zap.String("defaultPolicy", req.DefaultPolicy))
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]string{"defaultPolicy": req.DefaultPolicy})
 }
+
 func (s *APIService) updateIPFilter(w http.ResponseWriter, r *http.Request) {
-	// TODO: Update IP filter
+	// TODO: Update IP filter in place (delete + add covers current needs)
 	httputils.RespondJSON(w, http.StatusNotImplemented, map[string]string{"message": "updateIPFilter not implemented"})
 }
+
 func (s *APIService) deleteIPFilter(w http.ResponseWriter, r *http.Request) {
-	// TODO: Delete IP filter
-	httputils.RespondJSON(w, http.StatusNotImplemented, map[string]string{"message": "deleteIPFilter not implemented"})
+	if !s.requireIPFilter(w, r) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if !s.IPFilter.RemoveRule(id) {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusNotFound, fmt.Errorf("IP filter rule not found: %s", id))
+		return
+	}
+	s.Logger.Info("Deleted IP filter rule", zap.String("id", id))
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]string{"result": "rule deleted", "id": id})
+}
+
+func ipFilterPolicyName(allow bool) string {
+	if allow {
+		return "allow"
+	}
+	return "deny"
 }
 func (s *APIService) listWAFRules(w http.ResponseWriter, r *http.Request) {
 	// TODO: List WAF rules
@@ -381,6 +999,128 @@ func (s *APIService) deleteWAFRule(w http.ResponseWriter, r *http.Request) {
 	httputils.RespondJSON(w, http.StatusNotImplemented, map[string]string{"message": "deleteWAFRule not implemented"})
 }
 
+// stateSnapshotVersion identifies the export format so future imports can
+// reject snapshots they don't understand.
+const stateSnapshotVersion = 1
+
+// StateSnapshotServer is one backend in an exported state snapshot.
+type StateSnapshotServer struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	Weight            int               `json:"weight"`
+	Tags              map[string]string `json:"tags,omitempty"`
+	HealthCheckURL    string            `json:"healthCheckUrl,omitempty"`
+	Status            string            `json:"status"`
+	ActiveConnections int64             `json:"activeConnections"`
+}
+
+// StateSnapshot is the full runtime state exported by the state endpoints.
+// Subsystems that only exist as configuration stubs today (routing rules,
+// rate limits, IP filters, WAF rules, session settings) gain sections here
+// as they grow runtime state.
+type StateSnapshot struct {
+	Version    int                   `json:"version"`
+	ExportedAt string                `json:"exportedAt"`
+	Algorithm  string                `json:"algorithm"`
+	Servers    []StateSnapshotServer `json:"servers"`
+}
+
+func (s *APIService) exportState(w http.ResponseWriter, r *http.Request) {
+	servers := s.Pool.GetServers()
+	snapshot := StateSnapshot{
+		Version:    stateSnapshotVersion,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Algorithm:  s.currentAlgorithm(),
+		Servers:    make([]StateSnapshotServer, 0, len(servers)),
+	}
+	for _, srv := range servers {
+		entry := StateSnapshotServer{
+			ID:                srv.ID,
+			URL:               srv.URL.String(),
+			Weight:            srv.Weight,
+			Tags:              srv.Tags,
+			Status:            string(srv.HealthStatus),
+			ActiveConnections: srv.GetActiveConnections(),
+		}
+		if srv.HealthCheckURL != nil {
+			entry.HealthCheckURL = srv.HealthCheckURL.String()
+		}
+		snapshot.Servers = append(snapshot.Servers, entry)
+	}
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, snapshot)
+}
+
+func (s *APIService) importState(w http.ResponseWriter, r *http.Request) {
+	var snapshot StateSnapshot
+	if err := json.NewDecoder(r.Body).Decode(&snapshot); err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	if snapshot.Version != stateSnapshotVersion {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("unsupported snapshot version %d, expected %d", snapshot.Version, stateSnapshotVersion))
+		return
+	}
+	algo, err := s.algorithmByName(snapshot.Algorithm)
+	if err != nil {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Validate every server before touching the pool so a broken snapshot
+	// never leaves it half-applied. That includes the pool size cap and
+	// duplicate IDs: once the old membership is removed below, the re-adds
+	// must not be able to fail.
+	if max := s.Pool.MaxBackends(); max > 0 && len(snapshot.Servers) > max {
+		httputils.RespondErrorCtx(r.Context(), w, http.StatusConflict, fmt.Errorf("snapshot has %d servers but maxBackends is %d", len(snapshot.Servers), max))
+		return
+	}
+	seen := make(map[string]bool, len(snapshot.Servers))
+	restored := make([]*model.BackendServer, 0, len(snapshot.Servers))
+	for _, entry := range snapshot.Servers {
+		if seen[entry.ID] {
+			httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("duplicate server ID %q in snapshot", entry.ID))
+			return
+		}
+		seen[entry.ID] = true
+		server, err := model.NewBackendServer(entry.ID, entry.URL, entry.Weight)
+		if err != nil {
+			httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("invalid server %q in snapshot: %w", entry.ID, err))
+			return
+		}
+		server.Weight = entry.Weight // Preserve drained (zero-weight) servers as exported
+		server.Tags = entry.Tags
+		if entry.HealthCheckURL != "" {
+			if err := server.SetHealthCheckEndpoint(entry.HealthCheckURL, 0); err != nil {
+				httputils.RespondErrorCtx(r.Context(), w, http.StatusBadRequest, fmt.Errorf("invalid health check URL for server %q: %w", entry.ID, err))
+				return
+			}
+		}
+		server.SetStatus(model.HealthStatus(entry.Status))
+		restored = append(restored, server)
+	}
+
+	// Replace pool membership with the snapshot's. Everything was validated
+	// above, so these adds cannot fail and leave the pool half-populated.
+	for _, existing := range s.Pool.GetServers() {
+		s.Pool.RemoveServer(existing.ID)
+	}
+	for _, server := range restored {
+		s.Pool.AddServer(server)
+	}
+	s.Pool.SetAlgorithm(algo)
+	config.RuntimeLock()
+	s.Config.LoadBalancingAlgorithm = snapshot.Algorithm
+	config.RuntimeUnlock()
+
+	s.Logger.Info("Imported state snapshot",
+		zap.String("algorithm", snapshot.Algorithm),
+		zap.Int("servers", len(restored)))
+	httputils.RespondSuccessCtx(r.Context(), w, map[string]interface{}{
+		"algorithm": snapshot.Algorithm,
+		"servers":   len(restored),
+	}, "State snapshot imported")
+}
+
 func errServerNotFound(id string) error {
 	return &serverNotFoundError{id}
 }
@@ -458,107 +1198,108 @@ func (s *APIService) healthCheck(w http.ResponseWriter, r *http.Request) {
 		"version":        "v2.4.1",
 	}
 
-	httputils.RespondJSON(w, http.StatusOK, response)
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, response)
 }
 
 // Ping endpoint
 func (s *APIService) ping(w http.ResponseWriter, r *http.Request) {
-	httputils.RespondJSON(w, http.StatusOK, map[string]string{
+	httputils.RespondDataCtx(r.Context(), w, http.StatusOK, map[string]string{
 		"message": "pong",
 		"status":  "ok",
 	})
 }
 
 // Diagnostics endpoint
+// diagnostics runs the comprehensive diagnostics suite in-process and feeds
+// backend health straight from the running pool rather than re-probing.
 func (s *APIService) diagnostics(w http.ResponseWriter, r *http.Request) {
-	// Import diagnostics package
-	// Note: This would need to be added to imports at the top of the file
-	// For now, we'll provide a simplified diagnostic response
-
-	servers := s.Pool.GetServers()
-	healthyCount := 0
-	unhealthyServers := []string{}
-
-	for _, srv := range servers {
-		if srv.IsAlive() {
-			healthyCount++
-		} else {
-			unhealthyServers = append(unhealthyServers, srv.ID)
-		}
-	}
-
-	// Basic system diagnostics
-	diagnostics := map[string]interface{}{
-		"timestamp": time.Now().Format(time.RFC3339),
-		"services": map[string]interface{}{
-			"loadBalancer": map[string]interface{}{
-				"port":   s.Config.LoadBalancerPort,
-				"status": "running",
-			},
-			"api": map[string]interface{}{
-				"port":   s.Config.ApiPort,
-				"status": "running",
-			},
-		},
-		"backendServers": map[string]interface{}{
-			"total":            len(servers),
-			"healthy":          healthyCount,
-			"unhealthy":        len(servers) - healthyCount,
-			"unhealthyServers": unhealthyServers,
-		},
-		"configuration": map[string]interface{}{
-			"algorithm":        s.Config.LoadBalancingAlgorithm,
-			"loadBalancerPort": s.Config.LoadBalancerPort,
-			"apiPort":          s.Config.ApiPort,
-		},
-		"suggestions": []string{},
+	s.diagMu.Lock()
+	if s.diagCached != nil && time.Since(s.diagCachedAt) < diagnosticsCacheTTL {
+		info := s.diagCached
+		s.diagMu.Unlock()
+		httputils.RespondSuccessCtx(r.Context(), w, info, "System diagnostics retrieved successfully")
+		return
 	}
+	s.diagMu.Unlock()
 
-	// Add suggestions based on system state
-	suggestions := []string{}
-	if len(servers) == 0 {
-		suggestions = append(suggestions, "No backend servers configured - add servers via /api/v1/servers")
-	}
-	if healthyCount == 0 && len(servers) > 0 {
-		suggestions = append(suggestions, "All backend servers are unhealthy - check server connectivity")
-	}
-	if len(unhealthyServers) > 0 {
-		suggestions = append(suggestions, fmt.Sprintf("Some servers are unhealthy: %v - check server status", unhealthyServers))
+	configPath := os.Getenv("BACKEND_CONFIG_PATH")
+	if configPath == "" {
+		configPath = "backend/configs/config.yaml"
 	}
 
-	diagnostics["suggestions"] = suggestions
-
-	// Determine overall health status
-	status := "healthy"
-	if len(servers) == 0 {
-		status = "warning"
-	} else if healthyCount == 0 {
-		status = "critical"
-	} else if len(unhealthyServers) > 0 {
-		status = "warning"
+	diag := diagnostics.NewDiagnostics(s.Logger)
+	config.RuntimeRLock()
+	ports := []int{s.Config.LoadBalancerPort, s.Config.ApiPort}
+	config.RuntimeRUnlock()
+	info := diag.RunComprehensiveDiagnostics(configPath, os.Args[0], ports)
+
+	for _, srv := range s.Pool.GetServers() {
+		info.Backends = append(info.Backends, diagnostics.BackendHealth{
+			ID:             srv.ID,
+			URL:            srv.URL.String(),
+			Status:         string(srv.HealthStatus),
+			ResponseTimeMs: srv.GetResponseTime(),
+		})
 	}
 
-	diagnostics["overallStatus"] = status
+	s.diagMu.Lock()
+	s.diagCached = info
+	s.diagCachedAt = time.Now()
+	s.diagMu.Unlock()
 
-	httputils.RespondSuccess(w, diagnostics, "System diagnostics retrieved successfully")
+	httputils.RespondSuccessCtx(r.Context(), w, info, "System diagnostics retrieved successfully")
 }
 
-// Metrics endpoint - provides real-time load balancer metrics
+// Metrics endpoint - provides real-time load balancer metrics. Backends are
+// iterated exactly once and system metrics are read once per call, so the
+// handler stays cheap even with very large pools.
 func (s *APIService) getMetrics(w http.ResponseWriter, r *http.Request) {
+	// Optional ?sections=servers,health filter: respond with only the named
+	// top-level sections. Unknown names are ignored; no filter means all.
+	requested := map[string]bool{}
+	if raw := r.URL.Query().Get("sections"); raw != "" {
+		for _, name := range strings.Split(raw, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				requested[strings.ToLower(name)] = true
+			}
+		}
+	}
+	wantSection := func(name string) bool {
+		return len(requested) == 0 || requested[name]
+	}
+
 	servers := s.Pool.GetServers()
 
-	// Calculate server metrics
+	// Single pass over the pool: health counts, connection totals, and the
+	// response-time sum for the average/percentile estimates below.
 	totalServers := len(servers)
 	healthyServers := 0
 	totalConnections := int64(0)
 	totalWeight := 0
+	aliveResponseTimeSum := 0.0
+	aliveServers := 0
 
 	serverMetrics := make([]map[string]interface{}, 0, len(servers))
+	healthCheckDetails := make([]map[string]interface{}, 0, len(servers))
+	var totalChecks, totalCheckFailures int64
 
 	for _, srv := range servers {
 		isHealthy := srv.IsAlive()
+		responseTime := srv.GetResponseTime()
+		checkStats := srv.GetHealthCheckStats()
+		totalChecks += checkStats.ChecksRun
+		totalCheckFailures += checkStats.Failures
+		healthCheckDetails = append(healthCheckDetails, map[string]interface{}{
+			"id":             srv.ID,
+			"checksRun":      checkStats.ChecksRun,
+			"failures":       checkStats.Failures,
+			"lastDurationMs": checkStats.LastDurationMs,
+			"avgDurationMs":  checkStats.AvgDurationMs,
+		})
 		if isHealthy {
 			healthyServers++
+			aliveResponseTimeSum += float64(responseTime)
+			aliveServers++
 		}
 
 		activeConnections := srv.GetActiveConnections()
@@ -570,13 +1311,15 @@ func (s *APIService) getMetrics(w http.ResponseWriter, r *http.Request) {
 			"url":               srv.URL.String(),
 			"healthy":           isHealthy,
 			"activeConnections": activeConnections,
+			"peakConnections":   srv.GetPeakConnections(),
+			"draining":          srv.IsDraining(),
 			"weight":            srv.Weight,
 			"status":            string(srv.HealthStatus),
-			"responseTime":      srv.GetResponseTime(), // Add real response time
+			"responseTime":      responseTime,
+			"responseTimeAvg":   srv.GetResponseTimeEWMA(),
 		})
 	}
 
-	// Calculate load balancer performance metrics
 	now := time.Now()
 
 	// Get real request metrics from the server pool
@@ -586,72 +1329,90 @@ func (s *APIService) getMetrics(w http.ResponseWriter, r *http.Request) {
 	// Get real geographic data
 	geographicStats := s.Pool.GetGeographicStats()
 
-	metrics := map[string]interface{}{
-		"timestamp": now.Format(time.RFC3339),
-		"loadBalancer": map[string]interface{}{
-			"algorithm":         s.Config.LoadBalancingAlgorithm,
-			"port":              s.Config.LoadBalancerPort,
-			"totalRequests":     totalRequests,
-			"activeConnections": totalConnections,
-			"requestsPerSecond": requestsPerSecond,
-			"averageResponseTime": func() float64 {
-				// Only show response time if we have actual requests and servers
-				if totalRequests == 0 || totalServers == 0 {
-					return 0.0
-				}
-				// Calculate based on server response times if available
-				totalResponseTime := 0.0
-				serverCount := 0
-				for _, srv := range servers {
-					if srv.IsAlive() {
-						totalResponseTime += float64(srv.GetResponseTime())
-						serverCount++
-					}
-				}
-				if serverCount > 0 {
-					return totalResponseTime / float64(serverCount)
-				}
-				return 0.0
-			}(),
-		},
-		"geographic": map[string]interface{}{
-			"countries":      geographicStats,
-			"totalCountries": len(geographicStats),
-			"topCountry": func() string {
-				if len(geographicStats) > 0 {
-					return geographicStats[0].Country
-				}
-				return "Unknown"
-			}(),
-			"distribution": func() []map[string]interface{} {
-				result := make([]map[string]interface{}, 0, len(geographicStats))
-				for _, stats := range geographicStats {
-					result = append(result, map[string]interface{}{
-						"country":     stats.Country,
-						"countryCode": stats.CountryCode,
-						"requests":    stats.Requests,
-						"percentage":  math.Round(stats.Percentage*10) / 10,
-						"lastSeen":    stats.LastSeen,
-					})
-				}
-				return result
-			}(),
-		},
-		"servers": map[string]interface{}{
-			"total":       totalServers,
-			"healthy":     healthyServers,
-			"unhealthy":   totalServers - healthyServers,
-			"totalWeight": totalWeight,
-			"details":     serverMetrics,
-		},
-		"system": map[string]interface{}{
+	// Response-time estimates all derive from the same single-pass average.
+	avgResponseTime := 0.0
+	if totalRequests > 0 && aliveServers > 0 {
+		avgResponseTime = aliveResponseTimeSum / float64(aliveServers)
+	}
+
+	shedRate := 0.0
+	if s.Shedder != nil {
+		shedRate = s.Shedder.ShedRate()
+	}
+
+	// Read each system figure once and reuse it below, skipping the /proc
+	// reads entirely when neither dependent section was asked for.
+	cpuUsage, memoryUsage := 0.0, 0.0
+	if wantSection("system") || wantSection("health") {
+		cpuUsage = getRealCPUUsage()
+		memoryUsage = getRealMemoryUsage()
+	}
+
+	throughput := 0.0
+	if requestsPerSecond > 0 {
+		throughput = requestsPerSecond * 3600 // Convert to requests/hour
+	}
+
+	overallHealth := "healthy"
+	switch {
+	case totalServers == 0:
+		overallHealth = "warning"
+	case healthyServers == 0:
+		overallHealth = "critical"
+	case float64(healthyServers)/float64(totalServers) < 0.5:
+		overallHealth = "warning"
+	}
+
+	cpuHealth := "healthy"
+	switch {
+	case cpuUsage > 80:
+		cpuHealth = "critical"
+	case cpuUsage > 60:
+		cpuHealth = "warning"
+	}
+
+	memoryHealth := "healthy"
+	switch {
+	case memoryUsage > 85:
+		memoryHealth = "critical"
+	case memoryUsage > 70:
+		memoryHealth = "warning"
+	}
+
+	topCountry := "Unknown"
+	if len(geographicStats) > 0 {
+		topCountry = geographicStats[0].Country
+	}
+	distribution := make([]map[string]interface{}, 0, len(geographicStats))
+	for _, stats := range geographicStats {
+		distribution = append(distribution, map[string]interface{}{
+			"country":     stats.Country,
+			"countryCode": stats.CountryCode,
+			"requests":    stats.Requests,
+			"percentage":  math.Round(stats.Percentage*10) / 10,
+			"lastSeen":    stats.LastSeen,
+		})
+	}
+
+	var connections map[string]map[string]int64
+	if s.ConnMetrics != nil {
+		connections = s.ConnMetrics.ConnectionMetrics()
+	}
+
+	selAvgMicros, selP99Micros, selSamples := s.Pool.GetSelectionLatency()
+
+	// The system section is the expensive one (several /proc and disk
+	// reads), so only assemble it when requested.
+	var systemSection map[string]interface{}
+	if wantSection("system") {
+		systemSection = map[string]interface{}{
 			"cpu": map[string]interface{}{
-				"usage":       getRealCPUUsage(),
+				"usage":       cpuUsage,
 				"cores":       getRealCPUCores(),
 				"temperature": getRealCPUTemperature(),
 			},
 			"memory": map[string]interface{}{
-				"usage":     getRealMemoryUsage(),
+				"usage":     memoryUsage,
 				"total":     getRealMemoryTotal(),
 				"available": getRealMemoryAvailable(),
 			},
@@ -666,106 +1427,76 @@ func (s *APIService) getMetrics(w http.ResponseWriter, r *http.Request) {
 				"latency":  10.0 + (float64(totalConnections) * 0.1), // Keep simulated for now
 			},
 			"uptime": getRealSystemUptime(),
+		}
+	}
+
+	config.RuntimeRLock()
+	algorithm := s.Config.LoadBalancingAlgorithm
+	lbPort := s.Config.LoadBalancerPort
+	config.RuntimeRUnlock()
+
+	metrics := map[string]interface{}{
+		"timestamp": now.Format(time.RFC3339),
+		"loadBalancer": map[string]interface{}{
+			"algorithm":           algorithm,
+			"port":                lbPort,
+			"totalRequests":       totalRequests,
+			"activeConnections":   totalConnections,
+			"shedRate":            shedRate,
+			"inFlightRequests":    s.Pool.GetInFlightRequests(),
+			"requestsPerSecond":   requestsPerSecond,
+			"averageResponseTime": avgResponseTime,
+			"selectionLatency": map[string]interface{}{
+				"avgMicros": math.Round(selAvgMicros*10) / 10,
+				"p99Micros": selP99Micros,
+				"samples":   selSamples,
+			},
+		},
+		"geographic": map[string]interface{}{
+			"countries":      geographicStats,
+			"totalCountries": len(geographicStats),
+			"topCountry":     topCountry,
+			"distribution":   distribution,
+		},
+		"servers": map[string]interface{}{
+			"total":            totalServers,
+			"healthy":          healthyServers,
+			"unhealthy":        totalServers - healthyServers,
+			"totalWeight":      totalWeight,
+			"unavailableCause": s.Pool.EmptySelectionCause(),
+			"details":          serverMetrics,
 		},
+		"system": systemSection,
 		"performance": map[string]interface{}{
-			"throughput": func() float64 {
-				// Calculate throughput based on actual requests per second
-				if requestsPerSecond > 0 {
-					return requestsPerSecond * 3600 // Convert to requests/hour
-				}
-				return 0.0
-			}(),
-			"errorRate": func() float64 {
-				// Only show error rate if we have actual requests and servers
-				if totalRequests == 0 || totalServers == 0 {
-					return 0.0
-				}
-				// For now, return 0 as we don't track errors yet
-				// TODO: Implement actual error tracking
-				return 0.0
-			}(),
-			"p95ResponseTime": func() float64 {
-				// Only show percentile response times if we have actual requests and servers
-				if totalRequests == 0 || totalServers == 0 {
-					return 0.0
-				}
-				// Calculate based on server response times if available
-				totalResponseTime := 0.0
-				serverCount := 0
-				for _, srv := range servers {
-					if srv.IsAlive() {
-						responseTime := float64(srv.GetResponseTime())
-						totalResponseTime += responseTime
-						serverCount++
-					}
-				}
-				if serverCount > 0 {
-					avgResponseTime := totalResponseTime / float64(serverCount)
-					// Estimate p95 as avg + 40% (rough approximation)
-					return math.Round((avgResponseTime*1.4)*10) / 10
-				}
-				return 0.0
-			}(),
-			"p99ResponseTime": func() float64 {
-				// Only show percentile response times if we have actual requests and servers
-				if totalRequests == 0 || totalServers == 0 {
-					return 0.0
-				}
-				// Calculate based on server response times if available
-				totalResponseTime := 0.0
-				serverCount := 0
-				for _, srv := range servers {
-					if srv.IsAlive() {
-						responseTime := float64(srv.GetResponseTime())
-						totalResponseTime += responseTime
-						serverCount++
-					}
-				}
-				if serverCount > 0 {
-					avgResponseTime := totalResponseTime / float64(serverCount)
-					// Estimate p99 as avg + 80% (rough approximation)
-					return math.Round((avgResponseTime*1.8)*10) / 10
-				}
-				return 0.0
-			}(),
+			"throughput": throughput,
+			"errorRate":  0.0, // TODO: Implement actual error tracking
+			// Percentile estimates derived from the single-pass average
+			// (p95 as avg + 40%, p99 as avg + 80%).
+			"p95ResponseTime": math.Round((avgResponseTime*1.4)*10) / 10,
+			"p99ResponseTime": math.Round((avgResponseTime*1.8)*10) / 10,
+		},
+		"healthChecks": map[string]interface{}{
+			"totalChecks":   totalChecks,
+			"totalFailures": totalCheckFailures,
+			"perBackend":    healthCheckDetails,
 		},
+		"connections": connections,
 		"health": map[string]interface{}{
-			"overall": func() string {
-				if totalServers == 0 {
-					return "warning"
-				}
-				if healthyServers == 0 {
-					return "critical"
-				}
-				if float64(healthyServers)/float64(totalServers) < 0.5 {
-					return "warning"
-				}
-				return "healthy"
-			}(),
-			"cpuHealth": func() string {
-				cpuUsage := getRealCPUUsage()
-				if cpuUsage > 80 {
-					return "critical"
-				}
-				if cpuUsage > 60 {
-					return "warning"
-				}
-				return "healthy"
-			}(),
-			"memoryHealth": func() string {
-				memoryUsage := getRealMemoryUsage()
-				if memoryUsage > 85 {
-					return "critical"
-				}
-				if memoryUsage > 70 {
-					return "warning"
-				}
-				return "healthy"
-			}(),
+			"overall":      overallHealth,
+			"cpuHealth":    cpuHealth,
+			"memoryHealth": memoryHealth,
 		},
 	}
 
-	httputils.RespondSuccess(w, metrics, "Load balancer metrics retrieved successfully")
+	if len(requested) > 0 {
+		for name := range metrics {
+			if name != "timestamp" && !requested[name] {
+				delete(metrics, name)
+			}
+		}
+	}
+
+	httputils.RespondSuccessCtx(r.Context(), w, metrics, "Load balancer metrics retrieved successfully")
 }
 
 // CPU usage tracking variables
@@ -775,6 +1506,17 @@ var (
 	lastCPUTime  time.Time
 )
 
+// SystemCPUUsage reports current system CPU usage percent, for callers
+// outside this package (e.g. load shedding).
+func SystemCPUUsage() float64 {
+	return getRealCPUUsage()
+}
+
+// SystemMemoryUsage reports current system memory usage percent.
+func SystemMemoryUsage() float64 {
+	return getRealMemoryUsage()
+}
+
 // Real system metrics functions
 func getRealCPUUsage() float64 {
 	// Read CPU usage from /proc/stat on Linux
@@ -5,14 +5,39 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/gorilla/mux"
 
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/config"
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/middleware"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/routing"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/session"
 )
 
+// decodeData unwraps the standard success envelope and decodes its data
+// payload into out.
+func decodeData(t *testing.T, body []byte, out interface{}) {
+	t.Helper()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("failed to decode response envelope: %v", err)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		t.Fatalf("failed to decode response data: %v", err)
+	}
+}
+
 func TestAPIService_listServers(t *testing.T) {
 	// Create test dependencies
 	algo := &loadbalancing.RoundRobinAlgorithm{}
@@ -39,9 +64,7 @@ func TestAPIService_listServers(t *testing.T) {
 	}
 
 	var servers []ServerResponse
-	if err := json.NewDecoder(w.Body).Decode(&servers); err != nil {
-		t.Errorf("Failed to decode response: %v", err)
-	}
+	decodeData(t, w.Body.Bytes(), &servers)
 
 	if len(servers) != 0 {
 		t.Errorf("Expected empty server list, got %d servers", len(servers))
@@ -86,3 +109,909 @@ func TestAPIService_addServer(t *testing.T) {
 		t.Errorf("Expected 1 server, got %d", len(servers))
 	}
 }
+
+func TestAPIService_validateConfig(t *testing.T) {
+	service := &APIService{
+		Pool:   loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}),
+		Config: &config.Config{APIKey: "test-key"},
+		Logger: zap.NewNop(),
+	}
+
+	validYAML := `
+loadBalancerPort: 8080
+apiPort: 8081
+loadBalancingAlgorithm: roundrobin
+backendServers:
+  - id: server1
+    url: http://localhost:9001
+    weight: 1
+healthCheck:
+  intervalSeconds: 5
+  timeoutSeconds: 3
+`
+	req := httptest.NewRequest("POST", "/api/v1/config/validate", bytes.NewReader([]byte(validYAML)))
+	w := httptest.NewRecorder()
+	service.validateConfig(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for valid config, got %d: %s", w.Code, w.Body.String())
+	}
+
+	invalidYAML := `
+loadBalancerPort: 99999
+apiPort: 8081
+loadBalancingAlgorithm: quantum
+backendServers:
+  - id: ""
+    url: not-a-url
+healthCheck:
+  intervalSeconds: 0
+  timeoutSeconds: 3
+`
+	req = httptest.NewRequest("POST", "/api/v1/config/validate", bytes.NewReader([]byte(invalidYAML)))
+	w = httptest.NewRecorder()
+	service.validateConfig(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for invalid config, got %d", w.Code)
+	}
+
+	var resp struct {
+		Valid    bool     `json:"valid"`
+		Problems []string `json:"problems"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Valid || len(resp.Problems) == 0 {
+		t.Errorf("Expected problems for invalid config, got %+v", resp)
+	}
+}
+
+func TestAPIService_getConfigRedactsAPIKey(t *testing.T) {
+	const secret = "super-secret-key"
+	service := &APIService{
+		Pool:   loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}),
+		Config: &config.Config{APIKey: secret, LoadBalancerPort: 8080},
+		Logger: zap.NewNop(),
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/config", nil)
+	w := httptest.NewRecorder()
+	service.getConfig(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), secret) {
+		t.Error("API key must not appear in the config response")
+	}
+}
+
+func TestAPIService_updateConfigDoesNotLogAPIKey(t *testing.T) {
+	const secret = "super-secret-key"
+	observed, recorded := observer.New(zap.InfoLevel)
+
+	service := &APIService{
+		Pool:   loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}),
+		Config: &config.Config{APIKey: secret, LoadBalancerPort: 8080, ApiPort: 8081},
+		Logger: zap.New(observed),
+	}
+
+	body := bytes.NewReader([]byte(`{"loadBalancerPort": 8080}`))
+	req := httptest.NewRequest("PUT", "/api/v1/config", body)
+	w := httptest.NewRecorder()
+	service.updateConfig(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), secret) {
+		t.Error("API key must not appear in the update response")
+	}
+	for _, entry := range recorded.All() {
+		if strings.Contains(entry.Message, secret) {
+			t.Error("API key must not appear in log messages")
+		}
+		for _, field := range entry.Context {
+			if strings.Contains(field.String, secret) {
+				t.Errorf("API key must not appear in log field %q", field.Key)
+			}
+		}
+	}
+}
+
+func TestAPIService_updateConfigRejectsLivePortChange(t *testing.T) {
+	service := &APIService{
+		Pool:   loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}),
+		Config: &config.Config{APIKey: "test-key", LoadBalancerPort: 8080, ApiPort: 8081},
+		Logger: zap.NewNop(),
+	}
+
+	body := bytes.NewReader([]byte(`{"loadBalancerPort": 9090}`))
+	req := httptest.NewRequest("PUT", "/api/v1/config", body)
+	w := httptest.NewRecorder()
+	service.updateConfig(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a live port change, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "restart") {
+		t.Error("Expected the response to explain that a restart is required")
+	}
+	if service.Config.LoadBalancerPort != 8080 {
+		t.Errorf("Expected config port to stay 8080, got %d", service.Config.LoadBalancerPort)
+	}
+}
+
+func TestAPIService_listServersFiltersByTag(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	service := &APIService{
+		Pool:   pool,
+		Config: &config.Config{APIKey: "test-key"},
+		Logger: zap.NewNop(),
+	}
+
+	// Add one canary and one stable server through the API
+	for _, spec := range []AddServerRequest{
+		{ID: "canary-1", URL: "http://localhost:9001", Weight: 1, Tags: map[string]string{"track": "canary", "region": "eu"}},
+		{ID: "stable-1", URL: "http://localhost:9002", Weight: 1, Tags: map[string]string{"track": "stable"}},
+	} {
+		body, _ := json.Marshal(spec)
+		req := httptest.NewRequest("POST", "/api/v1/servers", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		service.addServer(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201 adding %s, got %d", spec.ID, w.Code)
+		}
+	}
+
+	tests := []struct {
+		query   string
+		wantIDs []string
+	}{
+		{"/api/v1/servers?tag=track=canary", []string{"canary-1"}},
+		{"/api/v1/servers?tag=region", []string{"canary-1"}},
+		{"/api/v1/servers?tag=track=stable", []string{"stable-1"}},
+		{"/api/v1/servers?tag=track=missing", []string{}},
+		{"/api/v1/servers", []string{"canary-1", "stable-1"}},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.query, nil)
+		w := httptest.NewRecorder()
+		service.listServers(w, req)
+
+		var servers []ServerResponse
+		decodeData(t, w.Body.Bytes(), &servers)
+		if len(servers) != len(tt.wantIDs) {
+			t.Errorf("%s: expected %d servers, got %d", tt.query, len(tt.wantIDs), len(servers))
+			continue
+		}
+		for i, want := range tt.wantIDs {
+			if servers[i].ID != want {
+				t.Errorf("%s: expected server %s at index %d, got %s", tt.query, want, i, servers[i].ID)
+			}
+		}
+	}
+}
+
+func TestAPIService_bulkMaintenanceByTag(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	service := &APIService{
+		Pool:   pool,
+		Config: &config.Config{APIKey: "test-key"},
+		Logger: zap.NewNop(),
+	}
+
+	specs := []struct {
+		id   string
+		tags map[string]string
+	}{
+		{"canary-1", map[string]string{"track": "canary"}},
+		{"canary-2", map[string]string{"track": "canary"}},
+		{"stable-1", map[string]string{"track": "stable"}},
+	}
+	for i, spec := range specs {
+		srv, err := model.NewBackendServer(spec.id, "http://localhost:900"+strconv.Itoa(i), 1)
+		if err != nil {
+			t.Fatalf("failed to create backend server: %v", err)
+		}
+		srv.Tags = spec.tags
+		srv.SetStatus(model.HEALTHY)
+		pool.AddServer(srv)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/maintenance?tag=track=canary", nil)
+	w := httptest.NewRecorder()
+	service.setMaintenanceByTag(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "canary-1") || !strings.Contains(w.Body.String(), "canary-2") {
+		t.Error("expected affected server IDs in the response")
+	}
+
+	for _, srv := range pool.GetServers() {
+		switch srv.ID {
+		case "canary-1", "canary-2":
+			if srv.HealthStatus != model.MAINTENANCE {
+				t.Errorf("expected %s in MAINTENANCE, got %s", srv.ID, srv.HealthStatus)
+			}
+		case "stable-1":
+			if srv.HealthStatus != model.HEALTHY {
+				t.Errorf("expected stable-1 untouched, got %s", srv.HealthStatus)
+			}
+		}
+	}
+
+	// Clearing returns only the matching servers to the health check cycle
+	req = httptest.NewRequest("DELETE", "/api/v1/maintenance?tag=track=canary", nil)
+	w = httptest.NewRecorder()
+	service.clearMaintenanceByTag(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	for _, srv := range pool.GetServers() {
+		if (srv.ID == "canary-1" || srv.ID == "canary-2") && srv.HealthStatus == model.MAINTENANCE {
+			t.Errorf("expected %s out of MAINTENANCE after clear", srv.ID)
+		}
+	}
+
+	// Missing tag parameter is a client error
+	w = httptest.NewRecorder()
+	service.setMaintenanceByTag(w, httptest.NewRequest("POST", "/api/v1/maintenance", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without a tag, got %d", w.Code)
+	}
+}
+
+func TestAPIService_diagnosticsReturnsStructuredInfo(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	srv, err := model.NewBackendServer("diag-1", "http://localhost:9001", 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	srv.SetStatus(model.HEALTHY)
+	pool.AddServer(srv)
+
+	service := &APIService{
+		Pool:   pool,
+		Config: &config.Config{APIKey: "test-key", LoadBalancerPort: 18080, ApiPort: 18081},
+		Logger: zap.NewNop(),
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/diagnostics", nil)
+	w := httptest.NewRecorder()
+	service.diagnostics(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var resp struct {
+		Data struct {
+			System struct {
+				OS        string `json:"os"`
+				GoVersion string `json:"goVersion"`
+			} `json:"system"`
+			Network struct {
+				Ports []struct {
+					Port int `json:"port"`
+				} `json:"ports"`
+			} `json:"network"`
+			Backends []struct {
+				ID     string `json:"id"`
+				Status string `json:"status"`
+			} `json:"backends"`
+			Suggestions []string `json:"suggestions"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Data.System.OS == "" || resp.Data.System.GoVersion == "" {
+		t.Error("expected populated system info")
+	}
+	if len(resp.Data.Network.Ports) != 2 {
+		t.Errorf("expected 2 checked ports, got %d", len(resp.Data.Network.Ports))
+	}
+	if len(resp.Data.Backends) != 1 || resp.Data.Backends[0].ID != "diag-1" || resp.Data.Backends[0].Status != "HEALTHY" {
+		t.Errorf("expected pool-fed backend health, got %+v", resp.Data.Backends)
+	}
+}
+
+func TestAPIService_stateExportImportRoundTrip(t *testing.T) {
+	pool := loadbalancing.NewServerPool(loadbalancing.NewWeightedRoundRobinAlgorithm())
+	service := &APIService{
+		Pool:   pool,
+		Config: &config.Config{APIKey: "test-key", LoadBalancingAlgorithm: "weighted"},
+		Logger: zap.NewNop(),
+	}
+
+	server1, _ := model.NewBackendServer("export-1", "http://localhost:9001", 3)
+	server1.Tags = map[string]string{"region": "eu"}
+	server1.SetStatus(model.HEALTHY)
+	server2, _ := model.NewBackendServer("export-2", "http://localhost:9002", 1)
+	server2.SetStatus(model.MAINTENANCE)
+	pool.AddServer(server1)
+	pool.AddServer(server2)
+
+	// Export the current state
+	w := httptest.NewRecorder()
+	service.exportState(w, httptest.NewRequest("GET", "/api/v1/state/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 from export, got %d", w.Code)
+	}
+	var snapshot StateSnapshot
+	decodeData(t, w.Body.Bytes(), &snapshot)
+	if len(snapshot.Servers) != 2 || snapshot.Algorithm != "weighted" {
+		t.Fatalf("unexpected snapshot contents: %+v", snapshot)
+	}
+
+	// Drift away from the exported state
+	pool.RemoveServer("export-2")
+	service.Config.LoadBalancingAlgorithm = "roundrobin"
+
+	// Import the snapshot and verify the state is restored
+	body, _ := json.Marshal(snapshot)
+	w = httptest.NewRecorder()
+	service.importState(w, httptest.NewRequest("POST", "/api/v1/state/import", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 from import, got %d: %s", w.Code, w.Body.String())
+	}
+	if service.Config.LoadBalancingAlgorithm != "weighted" {
+		t.Errorf("expected algorithm restored to weighted, got %s", service.Config.LoadBalancingAlgorithm)
+	}
+	servers := pool.GetServers()
+	if len(servers) != 2 {
+		t.Fatalf("expected 2 servers after import, got %d", len(servers))
+	}
+	byID := map[string]*model.BackendServer{}
+	for _, srv := range servers {
+		byID[srv.ID] = srv
+	}
+	if srv := byID["export-1"]; srv == nil || srv.Weight != 3 || srv.Tags["region"] != "eu" || srv.HealthStatus != model.HEALTHY {
+		t.Errorf("server export-1 not restored faithfully: %+v", srv)
+	}
+	if srv := byID["export-2"]; srv == nil || srv.HealthStatus != model.MAINTENANCE {
+		t.Errorf("server export-2 not restored faithfully: %+v", srv)
+	}
+}
+
+func TestAPIService_importStateRejectsInvalidSnapshot(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	existing, _ := model.NewBackendServer("keep-me", "http://localhost:9001", 1)
+	pool.AddServer(existing)
+	service := &APIService{
+		Pool:   pool,
+		Config: &config.Config{APIKey: "test-key", LoadBalancingAlgorithm: "roundrobin"},
+		Logger: zap.NewNop(),
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"wrong version", `{"version":99,"algorithm":"roundrobin","servers":[]}`},
+		{"unknown algorithm", `{"version":1,"algorithm":"bogus","servers":[]}`},
+		{"invalid server ID", `{"version":1,"algorithm":"roundrobin","servers":[{"id":"bad id","url":"http://localhost:9002","weight":1}]}`},
+		{"duplicate server IDs", `{"version":1,"algorithm":"roundrobin","servers":[{"id":"dup","url":"http://localhost:9002","weight":1},{"id":"dup","url":"http://localhost:9003","weight":1}]}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		service.importState(w, httptest.NewRequest("POST", "/api/v1/state/import", strings.NewReader(tt.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.name, w.Code)
+		}
+		if len(pool.GetServers()) != 1 {
+			t.Errorf("%s: rejected import must not modify the pool", tt.name)
+		}
+	}
+}
+
+func TestAPIService_importStateRejectsSnapshotOverPoolCap(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	pool.SetMaxBackends(1)
+	existing, _ := model.NewBackendServer("keep-me", "http://localhost:9001", 1)
+	pool.AddServer(existing)
+	service := &APIService{
+		Pool:   pool,
+		Config: &config.Config{APIKey: "test-key", LoadBalancingAlgorithm: "roundrobin"},
+		Logger: zap.NewNop(),
+	}
+
+	body := `{"version":1,"algorithm":"roundrobin","servers":[{"id":"a","url":"http://localhost:9002","weight":1},{"id":"b","url":"http://localhost:9003","weight":1}]}`
+	w := httptest.NewRecorder()
+	service.importState(w, httptest.NewRequest("POST", "/api/v1/state/import", strings.NewReader(body)))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409 for snapshot over the pool cap, got %d", w.Code)
+	}
+
+	// The oversized snapshot must be rejected up front, not mid-apply
+	servers := pool.GetServers()
+	if len(servers) != 1 || servers[0].ID != "keep-me" {
+		t.Errorf("rejected import must leave the pool untouched, got %d servers", len(servers))
+	}
+}
+
+func TestAPIService_getServerConnections(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, _ := model.NewBackendServer("conn-1", "http://localhost:9001", 1)
+	pool.AddServer(server)
+	service := &APIService{
+		Pool:   pool,
+		Config: &config.Config{APIKey: "test-key"},
+		Logger: zap.NewNop(),
+	}
+
+	// Two concurrent requests, then one finishes
+	server.IncrementConnections()
+	server.IncrementConnections()
+	server.DecrementConnections()
+
+	router := mux.NewRouter()
+	service.RegisterRoutes(router)
+	req := httptest.NewRequest("GET", "/api/v1/servers/conn-1/connections", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var body map[string]interface{}
+	decodeData(t, w.Body.Bytes(), &body)
+	if body["activeConnections"].(float64) != 1 {
+		t.Errorf("expected 1 active connection, got %v", body["activeConnections"])
+	}
+	if body["peakConnections"].(float64) != 2 {
+		t.Errorf("expected peak of 2 connections, got %v", body["peakConnections"])
+	}
+	if body["totalRequests"].(float64) != 2 {
+		t.Errorf("expected 2 total requests, got %v", body["totalRequests"])
+	}
+}
+
+func TestAPIService_getServerConnectionsNotFound(t *testing.T) {
+	service := &APIService{
+		Pool:   loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}),
+		Config: &config.Config{APIKey: "test-key"},
+		Logger: zap.NewNop(),
+	}
+	router := mux.NewRouter()
+	service.RegisterRoutes(router)
+	req := httptest.NewRequest("GET", "/api/v1/servers/no-such/connections", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+type fakeMaintenanceSwitch struct {
+	enabled bool
+}
+
+func (f *fakeMaintenanceSwitch) SetMaintenanceMode(on bool) { f.enabled = on }
+func (f *fakeMaintenanceSwitch) InMaintenanceMode() bool    { return f.enabled }
+
+func TestAPIService_maintenanceModeToggle(t *testing.T) {
+	sw := &fakeMaintenanceSwitch{}
+	service := &APIService{
+		Pool:        loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}),
+		Config:      &config.Config{APIKey: "test-key"},
+		Logger:      zap.NewNop(),
+		Maintenance: sw,
+	}
+
+	w := httptest.NewRecorder()
+	service.setMaintenanceMode(w, httptest.NewRequest("PUT", "/api/v1/maintenance-mode", strings.NewReader(`{"enabled":true}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if !sw.enabled {
+		t.Error("expected maintenance mode to be enabled")
+	}
+
+	w = httptest.NewRecorder()
+	service.getMaintenanceMode(w, httptest.NewRequest("GET", "/api/v1/maintenance-mode", nil))
+	if !strings.Contains(w.Body.String(), `"enabled":true`) {
+		t.Errorf("expected enabled:true in response, got %s", w.Body.String())
+	}
+}
+
+func TestAPIService_listAlgorithmsCapabilities(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	cfg := &config.Config{LoadBalancingAlgorithm: "weighted"}
+	service := &APIService{Pool: pool, Config: cfg, Logger: zap.NewNop()}
+
+	req := httptest.NewRequest("GET", "/api/v1/algorithms", nil)
+	w := httptest.NewRecorder()
+	service.listAlgorithms(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp struct {
+		Current    string          `json:"current"`
+		Algorithms []AlgorithmInfo `json:"algorithms"`
+	}
+	decodeData(t, w.Body.Bytes(), &resp)
+	if resp.Current != "weighted" {
+		t.Errorf("Expected current algorithm weighted, got %q", resp.Current)
+	}
+
+	byName := make(map[string]AlgorithmInfo, len(resp.Algorithms))
+	for _, algo := range resp.Algorithms {
+		byName[algo.Name] = algo
+	}
+	weighted, ok := byName["weighted"]
+	if !ok {
+		t.Fatal("Expected weighted in the algorithm list")
+	}
+	if !weighted.SupportsWeights || !weighted.Stateful {
+		t.Errorf("Expected weighted to support weights and be stateful, got %+v", weighted)
+	}
+	ipHash, ok := byName["iphash"]
+	if !ok {
+		t.Fatal("Expected iphash in the algorithm list")
+	}
+	if !ipHash.SupportsAffinity || ipHash.SupportsWeights {
+		t.Errorf("Expected iphash to support affinity but not weights, got %+v", ipHash)
+	}
+}
+
+func TestAPIService_listServersReportsDraining(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	service := &APIService{Pool: pool, Config: &config.Config{}, Logger: zap.NewNop()}
+
+	active, err := model.NewBackendServer("active-1", "http://localhost:8081", 2)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	draining, err := model.NewBackendServer("draining-1", "http://localhost:8082", 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	draining.Weight = 0 // Drained out of rotation
+	pool.AddServer(active)
+	pool.AddServer(draining)
+
+	w := httptest.NewRecorder()
+	service.listServers(w, httptest.NewRequest("GET", "/api/v1/servers", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var servers []ServerResponse
+	decodeData(t, w.Body.Bytes(), &servers)
+	byID := make(map[string]ServerResponse, len(servers))
+	for _, srv := range servers {
+		byID[srv.ID] = srv
+	}
+	if byID["draining-1"].Draining != true {
+		t.Error("Expected zero-weight server to report draining")
+	}
+	if byID["active-1"].Draining {
+		t.Error("Expected weighted server to not report draining")
+	}
+}
+
+func TestAPIService_getServerHistory(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	service := &APIService{Pool: pool, Config: &config.Config{}, Logger: zap.NewNop()}
+
+	server, err := model.NewBackendServer("hist-1", "http://localhost:9001", 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	pool.AddServer(server)
+	pool.SetBackendStatusWithReason("hist-1", model.HEALTHY, "health checks passing")
+	pool.SetBackendStatusWithReason("hist-1", model.UNHEALTHY, "connection refused")
+
+	router := mux.NewRouter()
+	service.RegisterRoutes(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/servers/hist-1/history", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var body struct {
+		ID      string                   `json:"id"`
+		History []model.HealthTransition `json:"history"`
+	}
+	decodeData(t, w.Body.Bytes(), &body)
+	if len(body.History) != 2 {
+		t.Fatalf("expected 2 transitions, got %d: %+v", len(body.History), body.History)
+	}
+	first, second := body.History[0], body.History[1]
+	if first.From != model.UNHEALTHY || first.To != model.HEALTHY {
+		t.Errorf("unexpected first transition: %+v", first)
+	}
+	if second.From != model.HEALTHY || second.To != model.UNHEALTHY || second.Reason != "connection refused" {
+		t.Errorf("unexpected second transition: %+v", second)
+	}
+	if second.Timestamp.Before(first.Timestamp) {
+		t.Error("expected transitions in chronological order")
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/servers/missing/history", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown server, got %d", w.Code)
+	}
+}
+
+func BenchmarkGetMetricsLargePool(b *testing.B) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	for i := 0; i < 500; i++ {
+		server, err := model.NewBackendServer("bench-"+strconv.Itoa(i), "http://localhost:9000", 1)
+		if err != nil {
+			b.Fatalf("failed to create server: %v", err)
+		}
+		server.SetStatus(model.HEALTHY)
+		pool.AddServer(server)
+	}
+	service := &APIService{Pool: pool, Config: &config.Config{}, Logger: zap.NewNop()}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		service.getMetrics(w, httptest.NewRequest("GET", "/api/v1/metrics", nil))
+		if w.Code != http.StatusOK {
+			b.Fatalf("expected status 200, got %d", w.Code)
+		}
+	}
+}
+
+func TestAPIService_setServerWeightShiftsDistribution(t *testing.T) {
+	pool := loadbalancing.NewServerPool(loadbalancing.NewWeightedRoundRobinAlgorithm())
+	service := &APIService{Pool: pool, Config: &config.Config{}, Logger: zap.NewNop()}
+
+	heavy, _ := model.NewBackendServer("heavy-1", "http://localhost:9001", 1)
+	light, _ := model.NewBackendServer("light-1", "http://localhost:9002", 1)
+	heavy.SetStatus(model.HEALTHY)
+	light.SetStatus(model.HEALTHY)
+	pool.AddServer(heavy)
+	pool.AddServer(light)
+
+	router := mux.NewRouter()
+	service.RegisterRoutes(router)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PATCH", "/api/v1/servers/heavy-1/weight", strings.NewReader(`{"weight": 3}`))
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var updated ServerResponse
+	decodeData(t, w.Body.Bytes(), &updated)
+	if updated.Weight != 3 {
+		t.Errorf("expected updated weight 3, got %d", updated.Weight)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 40; i++ {
+		if srv := pool.SelectBackend(httptest.NewRequest("GET", "/", nil)); srv != nil {
+			counts[srv.ID]++
+		}
+	}
+	if counts["heavy-1"] != 30 || counts["light-1"] != 10 {
+		t.Errorf("expected a 3:1 split over 40 selections, got %v", counts)
+	}
+
+	// Bounds: negative weights are rejected.
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("PATCH", "/api/v1/servers/heavy-1/weight", strings.NewReader(`{"weight": -1}`))
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for negative weight, got %d", w.Code)
+	}
+}
+
+func TestAPIService_listSessionsReportsCounts(t *testing.T) {
+	sm := session.NewSessionManager()
+	b1 := &model.BackendServer{ID: "b1", HealthStatus: model.HEALTHY}
+	b2 := &model.BackendServer{ID: "b2", HealthStatus: model.HEALTHY}
+	for i := 0; i < 3; i++ {
+		sm.SetStickyServer(httptest.NewRecorder(), b1)
+	}
+	sm.SetStickyServer(httptest.NewRecorder(), b2)
+
+	service := &APIService{
+		Pool:     loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}),
+		Config:   &config.Config{APIKey: "test-key"},
+		Logger:   zap.NewNop(),
+		Sessions: sm,
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/sessions", nil)
+	w := httptest.NewRecorder()
+	service.listSessions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var data struct {
+		Sessions map[string]uint64 `json:"sessions"`
+		Total    uint64            `json:"total"`
+	}
+	decodeData(t, w.Body.Bytes(), &data)
+	if data.Sessions["b1"] != 3 || data.Sessions["b2"] != 1 {
+		t.Errorf("expected counts b1=3 b2=1, got %v", data.Sessions)
+	}
+	if data.Total != 4 {
+		t.Errorf("expected total 4, got %d", data.Total)
+	}
+}
+
+func TestAPIService_testRoutingRuleReportsFirstMatch(t *testing.T) {
+	router := routing.NewL7Router()
+	router.Pools["api"] = loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	router.Pools["catchall"] = loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	// Overlapping rules: both prefixes match /api/users, the first wins
+	router.Rules = []routing.RoutingRule{
+		{ID: "users", Host: "example.com", PathPrefix: "/api/users", TargetPoolID: "api"},
+		{ID: "everything", PathPrefix: "/api", TargetPoolID: "catchall"},
+	}
+
+	service := &APIService{
+		Pool:   loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}),
+		Config: &config.Config{APIKey: "test-key"},
+		Logger: zap.NewNop(),
+		Router: router,
+	}
+
+	body := `{"host":"example.com","path":"/api/users/42","method":"GET"}`
+	req := httptest.NewRequest("POST", "/api/v1/routing-rules/test", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	service.testRoutingRule(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result struct {
+		Matched      bool   `json:"matched"`
+		RuleID       string `json:"ruleId"`
+		TargetPoolID string `json:"targetPoolId"`
+	}
+	decodeData(t, w.Body.Bytes(), &result)
+	if !result.Matched || result.RuleID != "users" || result.TargetPoolID != "api" {
+		t.Errorf("expected the more specific rule to match first, got %+v", result)
+	}
+
+	// A different host falls through to the catch-all prefix rule
+	body = `{"host":"other.com","path":"/api/users/42"}`
+	req = httptest.NewRequest("POST", "/api/v1/routing-rules/test", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	service.testRoutingRule(w, req)
+	decodeData(t, w.Body.Bytes(), &result)
+	if !result.Matched || result.RuleID != "everything" {
+		t.Errorf("expected the catch-all rule to match, got %+v", result)
+	}
+
+	// No rule matches outside /api
+	body = `{"host":"example.com","path":"/static/app.js"}`
+	req = httptest.NewRequest("POST", "/api/v1/routing-rules/test", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	service.testRoutingRule(w, req)
+	decodeData(t, w.Body.Bytes(), &result)
+	if result.Matched {
+		t.Errorf("expected no match for an unrouted path, got %+v", result)
+	}
+}
+
+func TestAPIService_rotateAPIKey(t *testing.T) {
+	store := middleware.NewAPIKeyStore("initial-key-0123456789")
+	cfg := &config.Config{APIKey: "initial-key-0123456789"}
+	service := &APIService{
+		Pool:    loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}),
+		Config:  cfg,
+		Logger:  zap.NewNop(),
+		APIKeys: store,
+	}
+
+	body := `{"apiKey":"rotated-key-0123456789","graceSeconds":60}`
+	req := httptest.NewRequest("POST", "/api/v1/config/apikey", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	service.rotateAPIKey(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !store.Validate("rotated-key-0123456789") {
+		t.Error("expected the new key to validate after rotation")
+	}
+	if !store.Validate("initial-key-0123456789") {
+		t.Error("expected the old key to stay valid inside the grace window")
+	}
+	if cfg.APIKey != "rotated-key-0123456789" {
+		t.Errorf("expected the runtime config to track the new key, got %q", cfg.APIKey)
+	}
+
+	// Short keys are rejected
+	req = httptest.NewRequest("POST", "/api/v1/config/apikey", strings.NewReader(`{"apiKey":"short"}`))
+	w = httptest.NewRecorder()
+	service.rotateAPIKey(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a too-short key, got %d", w.Code)
+	}
+}
+
+func TestAPIService_diagnosticsCachedWithinTTL(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	service := &APIService{
+		Pool:   pool,
+		Config: &config.Config{APIKey: "test-key"},
+		Logger: zap.NewNop(),
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/diagnostics", nil)
+	w := httptest.NewRecorder()
+	service.diagnostics(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	// A pool change inside the TTL is not reflected: the cached result is
+	// served instead of being rebuilt per request. (The envelope timestamp
+	// differs per response, so compare on content.)
+	server, err := model.NewBackendServer("cached-1", "http://localhost:9001", 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	pool.AddServer(server)
+
+	w = httptest.NewRecorder()
+	service.diagnostics(w, req)
+	if strings.Contains(w.Body.String(), "cached-1") {
+		t.Error("expected a repeated call within the TTL to reuse the cached diagnostics result")
+	}
+
+	// Expiring the cache picks up the new backend
+	service.diagMu.Lock()
+	service.diagCachedAt = time.Now().Add(-2 * diagnosticsCacheTTL)
+	service.diagMu.Unlock()
+
+	w = httptest.NewRecorder()
+	service.diagnostics(w, req)
+	if !strings.Contains(w.Body.String(), "cached-1") {
+		t.Error("expected a rebuilt diagnostics result to include the new backend")
+	}
+}
+
+func TestAPIService_getMetricsSectionsFilter(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	service := &APIService{Pool: pool, Config: &config.Config{}, Logger: zap.NewNop()}
+
+	w := httptest.NewRecorder()
+	service.getMetrics(w, httptest.NewRequest("GET", "/api/v1/metrics?sections=servers,health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var metrics map[string]interface{}
+	decodeData(t, w.Body.Bytes(), &metrics)
+
+	for _, want := range []string{"timestamp", "servers", "health"} {
+		if _, ok := metrics[want]; !ok {
+			t.Errorf("expected section %q in filtered metrics", want)
+		}
+	}
+	for _, unwanted := range []string{"system", "loadBalancer", "geographic", "performance", "healthChecks"} {
+		if _, ok := metrics[unwanted]; ok {
+			t.Errorf("did not expect section %q with sections=servers,health", unwanted)
+		}
+	}
+
+	// No filter keeps the full payload
+	w = httptest.NewRecorder()
+	service.getMetrics(w, httptest.NewRequest("GET", "/api/v1/metrics", nil))
+	var full map[string]interface{}
+	decodeData(t, w.Body.Bytes(), &full)
+	for _, want := range []string{"servers", "system", "loadBalancer", "health"} {
+		if _, ok := full[want]; !ok {
+			t.Errorf("expected section %q in unfiltered metrics", want)
+		}
+	}
+}
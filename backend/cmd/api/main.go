@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
@@ -41,8 +42,15 @@ func main() {
 	apiRouter := mux.NewRouter()
 	apiService := &httpapi.APIService{Pool: serverPool, Config: cfg, Logger: log}
 	apiService.RegisterRoutes(apiRouter)
-	authMiddleware := middleware.APIKeyAuthMiddleware(apiRouter, cfg.APIKey)
+	if !cfg.APIAuthIsEnabled() {
+		log.Warn("API KEY AUTHENTICATION IS DISABLED; the management API accepts unauthenticated requests. Only do this behind a trusted gateway.")
+	}
+	apiKeys := middleware.NewAPIKeyStore(cfg.APIKey)
+	apiService.APIKeys = apiKeys
+	authMiddleware := middleware.RequestIDMiddleware(
+		middleware.OptionalAPIKeyAuthMiddlewareWithStore(apiRouter, apiKeys, cfg.APIKeyHeader, cfg.APIAuthIsEnabled()))
+	apiHandler := middleware.TimeoutMiddleware(authMiddleware, time.Duration(cfg.ApiRequestTimeoutSecs)*time.Second)
 
 	log.Info("API service started successfully", zap.Int("port", cfg.ApiPort))
-	log.Fatal("API server failed", zap.Error(http.ListenAndServe(fmt.Sprintf(":%d", cfg.ApiPort), authMiddleware)))
+	log.Fatal("API server failed", zap.Error(http.ListenAndServe(fmt.Sprintf(":%d", cfg.ApiPort), apiHandler)))
 }
@@ -8,6 +8,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/config"
 	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/diagnostics"
 )
 
@@ -42,6 +43,14 @@ func main() {
 	ports := []int{8080, 8081} // LoadBalancer and API ports
 	info := diag.RunComprehensiveDiagnostics(*configPath, *binaryPath, ports)
 
+	// Probe configured backends directly; without a running pool this is the
+	// only way the CLI can report backend health
+	if cfg, err := config.LoadConfig(*configPath); err == nil {
+		for _, backend := range cfg.BackendServers {
+			info.Backends = append(info.Backends, diag.ProbeBackend(backend.ID, backend.URL))
+		}
+	}
+
 	// Output results
 	if *jsonOutput {
 		if *outputFile != "" {
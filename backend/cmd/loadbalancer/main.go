@@ -36,7 +36,18 @@ func main() {
 		os.Exit(1)
 	}
 
-	logger.InitLogger(true) // true for debug logging in dev
+	if cfg.LogFile.Path != "" {
+		logger.InitLoggerWithFile(cfg.LogLevel, cfg.LogFormat, logger.FileConfig{
+			Path:       cfg.LogFile.Path,
+			MaxSizeMB:  cfg.LogFile.MaxSizeMB,
+			MaxBackups: cfg.LogFile.MaxBackups,
+			MaxAgeDays: cfg.LogFile.MaxAgeDays,
+		})
+	} else if cfg.LogLevel != "" || cfg.LogFormat != "" {
+		logger.InitLoggerWithOptions(cfg.LogLevel, cfg.LogFormat)
+	} else {
+		logger.InitLogger(true) // true for debug logging in dev
+	}
 	defer logger.Sync()
 
 	log := zap.L()
@@ -54,22 +65,64 @@ func main() {
 		algorithm = loadbalancing.NewWeightedRoundRobinAlgorithm()
 	case "weightedrandom":
 		algorithm = &loadbalancing.WeightedAlgorithm{}
+	case "adaptive":
+		adaptive := loadbalancing.NewAdaptiveAlgorithm()
+		if cfg.AdaptiveScoring.ConnectionWeight > 0 {
+			adaptive.ConnectionWeight = cfg.AdaptiveScoring.ConnectionWeight
+		}
+		if cfg.AdaptiveScoring.LatencyWeight > 0 {
+			adaptive.LatencyWeight = cfg.AdaptiveScoring.LatencyWeight
+		}
+		algorithm = adaptive
 	default:
 		algorithm = &loadbalancing.RoundRobinAlgorithm{}
 	}
 
+	if cfg.Zone != "" {
+		// Prefer same-zone backends; the wrapped algorithm still decides
+		// within the zone (or across zones when the local zone is empty).
+		algorithm = loadbalancing.NewZoneAffinityAlgorithm(cfg.Zone, algorithm)
+		log.Info("Zone affinity enabled", zap.String("zone", cfg.Zone))
+	}
+
 	log.Info("Initialized load balancing algorithm", zap.String("algorithm", cfg.LoadBalancingAlgorithm))
 	serverPool := loadbalancing.NewServerPoolWithLogger(algorithm, log)
+	if cfg.MaxBackends > 0 {
+		serverPool.SetMaxBackends(cfg.MaxBackends)
+	}
+	if cfg.GeoSampleRate > 1 {
+		serverPool.SetGeographicSampleRate(cfg.GeoSampleRate)
+		log.Info("Geographic tracking sampling enabled", zap.Int("sampleRate", cfg.GeoSampleRate))
+	}
 
-	// Populate server pool from config
-	for _, sCfg := range cfg.BackendServers {
-		server, err := model.NewBackendServer(sCfg.ID, sCfg.URL, sCfg.Weight)
+	// Populate server pool from config. Fractional config weights are scaled
+	// to proportional integers (e.g. 1.5:1 becomes 15:10) before use.
+	weights := make([]float64, len(cfg.BackendServers))
+	for i, sCfg := range cfg.BackendServers {
+		weights[i] = sCfg.Weight
+	}
+	intWeights := config.ScaleWeights(weights)
+	for i, sCfg := range cfg.BackendServers {
+		server, err := model.NewBackendServer(sCfg.ID, sCfg.URL, intWeights[i])
 		if err != nil {
 			log.Error("Failed to parse backend server URL", zap.Error(err), zap.String("url", sCfg.URL))
 			continue
 		}
-		serverPool.AddServer(server)
+		server.Tags = sCfg.Tags
+		server.ProxyProtocol = sCfg.ProxyProtocol
+		server.Zone = sCfg.Zone
+		if err := server.SetHealthCheckEndpoint(sCfg.HealthCheckURL, sCfg.HealthCheckPort); err != nil {
+			log.Error("Invalid health check endpoint", zap.Error(err), zap.String("id", server.ID))
+			continue
+		}
+		if err := serverPool.TryAddServer(server); err != nil {
+			log.Error("Failed to add backend server to pool", zap.Error(err), zap.String("id", server.ID))
+			continue
+		}
 		log.Info("Added backend server to pool", zap.String("id", server.ID), zap.String("url", server.URL.String()))
+		if cfg.WarmUpConnections > 0 {
+			go loadbalancing.WarmUpBackend(server, cfg.WarmUpConnections, log)
+		}
 	}
 
 	// 2. Start Health Checks
@@ -77,38 +130,157 @@ func main() {
 
 	// 3. Create core Load Balancer handler
 	lbHandler := core.NewLoadBalancer(serverPool, log)
+	if cfg.MaxInFlightRequests > 0 {
+		lbHandler.SetMaxInFlightRequests(cfg.MaxInFlightRequests)
+		log.Info("Global concurrency cap enabled", zap.Int("maxInFlightRequests", cfg.MaxInFlightRequests))
+	}
+	if cfg.ProxyError.StatusCode > 0 || cfg.ProxyError.Message != "" {
+		lbHandler.SetProxyErrorResponse(cfg.ProxyError.StatusCode, cfg.ProxyError.Message)
+	}
+	if len(cfg.StripResponseHeaders) > 0 {
+		lbHandler.SetStripResponseHeaders(cfg.StripResponseHeaders)
+	}
+	if cfg.ErrorEjection.Threshold > 0 {
+		window := time.Duration(cfg.ErrorEjection.WindowSeconds) * time.Second
+		if window <= 0 {
+			window = 30 * time.Second
+		}
+		lbHandler.ConfigureErrorRateEjection(cfg.ErrorEjection.Threshold, window, cfg.ErrorEjection.MinSamples)
+		log.Info("Error-rate ejection enabled",
+			zap.Float64("threshold", cfg.ErrorEjection.Threshold),
+			zap.Duration("window", window))
+	}
+	if cfg.RequestCoalescing {
+		lbHandler.EnableRequestCoalescing()
+		log.Info("Request coalescing enabled for identical concurrent GETs")
+	}
+	if cfg.StickyRebalance.Fraction > 0 {
+		window := time.Duration(cfg.StickyRebalance.WindowSeconds) * time.Second
+		if window <= 0 {
+			window = 5 * time.Minute
+		}
+		lbHandler.SessionMgr.EnableRebalancing(cfg.StickyRebalance.Fraction, window)
+		log.Info("Sticky session rebalancing enabled",
+			zap.Float64("fraction", cfg.StickyRebalance.Fraction),
+			zap.Duration("window", window))
+	}
+	if up := cfg.UpstreamConnections; up.MaxIdleConns > 0 || up.MaxIdleConnsPerHost > 0 || up.MaxConnsPerHost > 0 {
+		lbHandler.ConfigureUpstreamTransport(up.MaxIdleConns, up.MaxIdleConnsPerHost, up.MaxConnsPerHost)
+		log.Info("Upstream connection limits applied",
+			zap.Int("maxIdleConns", up.MaxIdleConns),
+			zap.Int("maxIdleConnsPerHost", up.MaxIdleConnsPerHost),
+			zap.Int("maxConnsPerHost", up.MaxConnsPerHost))
+	}
+	if secs := cfg.UpstreamConnections.IdleConnTimeoutSeconds; secs > 0 {
+		lbHandler.SetUpstreamIdleConnTimeout(time.Duration(secs) * time.Second)
+		log.Info("Upstream idle connection timeout applied", zap.Int("idleConnTimeoutSeconds", secs))
+	}
+	if cfg.UpstreamTimeoutSecs > 0 {
+		lbHandler.SetUpstreamTimeout(time.Duration(cfg.UpstreamTimeoutSecs) * time.Second)
+		log.Info("Upstream response timeout enabled", zap.Int("upstreamTimeoutSeconds", cfg.UpstreamTimeoutSecs))
+	}
+	if cfg.LoadShedding.Enabled {
+		cpuThresh := cfg.LoadShedding.CPUThresholdPercent
+		if cpuThresh <= 0 {
+			cpuThresh = 85
+		}
+		memThresh := cfg.LoadShedding.MemoryThresholdPercent
+		if memThresh <= 0 {
+			memThresh = 90
+		}
+		sampleSecs := cfg.LoadShedding.SampleIntervalSeconds
+		if sampleSecs <= 0 {
+			sampleSecs = 5
+		}
+		lbHandler.ConfigureLoadShedding(cpuThresh, memThresh, httpapi.SystemCPUUsage, httpapi.SystemMemoryUsage)
+		lbHandler.StartLoadShedding(time.Duration(sampleSecs) * time.Second)
+		log.Info("Load shedding enabled",
+			zap.Float64("cpuThresholdPercent", cpuThresh),
+			zap.Float64("memoryThresholdPercent", memThresh))
+	}
+	lbHandler.ConfigureMaintenanceResponse(cfg.Maintenance.RetryAfterSeconds, cfg.Maintenance.Message, cfg.Maintenance.AllowedPaths)
+	if cfg.Maintenance.Enabled {
+		lbHandler.SetMaintenanceMode(true)
+		log.Warn("Global maintenance mode is ENABLED; clients receive 503 until it is cleared")
+	}
+	if cfg.LogSampling.Enabled {
+		first, thereafter := cfg.LogSampling.First, cfg.LogSampling.Thereafter
+		if first <= 0 {
+			first = 100
+		}
+		if thereafter <= 0 {
+			thereafter = 100
+		}
+		lbHandler.ConfigureAccessLogSampling(time.Second, first, thereafter)
+		log.Info("Access-log sampling enabled", zap.Int("first", first), zap.Int("thereafter", thereafter))
+	}
 
 	// API router setup
 	apiRouter := mux.NewRouter()
-	apiService := &httpapi.APIService{Pool: serverPool, Config: cfg, Logger: log}
+	apiService := &httpapi.APIService{Pool: serverPool, Config: cfg, Logger: log, Maintenance: lbHandler, Shedder: lbHandler}
 	apiService.RegisterRoutes(apiRouter)
-	authMiddleware := middleware.APIKeyAuthMiddleware(apiRouter, cfg.APIKey)
+	if !cfg.APIAuthIsEnabled() {
+		log.Warn("API KEY AUTHENTICATION IS DISABLED; the management API accepts unauthenticated requests. Only do this behind a trusted gateway.")
+	}
+	apiKeys := middleware.NewAPIKeyStore(cfg.APIKey)
+	apiService.APIKeys = apiKeys
+	authMiddleware := middleware.RequestIDMiddleware(
+		middleware.OptionalAPIKeyAuthMiddlewareWithStore(apiRouter, apiKeys, cfg.APIKeyHeader, cfg.APIAuthIsEnabled()))
+
+	// Client IP filtering, evaluated before anything else on the LB port
+	var ipFilter *middleware.IPFilter
+	if cfg.IPFilter.Enabled {
+		ipFilter = middleware.NewIPFilter(cfg.IPFilter.DefaultPolicy != "deny")
+		for _, rule := range cfg.IPFilter.Rules {
+			if _, err := ipFilter.AddRule(rule.CIDR, middleware.IPFilterAction(rule.Action)); err != nil {
+				log.Error("Invalid IP filter rule", zap.Error(err), zap.String("cidr", rule.CIDR))
+			}
+		}
+		apiService.IPFilter = ipFilter
+		log.Info("IP filtering enabled", zap.String("defaultPolicy", cfg.IPFilter.DefaultPolicy), zap.Int("rules", len(ipFilter.Rules())))
+	}
 
 	// 4. Set up HTTP server for load balancing
 	limiter := rate.NewLimiter(10, 20) // 10 req/sec, burst 20
+	var lbChain http.Handler = middleware.RateLimitMiddleware(lbHandler, limiter)
+	if ipFilter != nil {
+		lbChain = middleware.IPFilterMiddleware(lbChain, ipFilter)
+	}
 	lbServer := &http.Server{
-		Addr: fmt.Sprintf(":%d", cfg.LoadBalancerPort),
-		Handler: middleware.RateLimitMiddleware(
-			lbHandler,
-			limiter,
-		),
+		Addr:         fmt.Sprintf(":%d", cfg.LoadBalancerPort),
+		Handler:      lbChain,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
 
 	// 4.1. Set up HTTP server for management API
+	apiHandler := middleware.TimeoutMiddleware(authMiddleware, time.Duration(cfg.ApiRequestTimeoutSecs)*time.Second)
 	apiServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.ApiPort),
-		Handler:      authMiddleware,
+		Handler:      apiHandler,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
 
 	// 5. Initialize ServerManager
+	server.ConfigureServerTimeouts(lbServer, cfg.ServerTimeouts)
+	server.ConfigureServerTimeouts(apiServer, cfg.ServerTimeouts)
+
 	serverManager := server.NewServerManager(cfg, log)
 	serverManager.SetServers(lbServer, apiServer)
+	serverManager.SetDrainer(lbHandler, time.Duration(cfg.ShutdownGraceSecs)*time.Second)
+	apiService.ConnMetrics = serverManager
+	apiService.Sessions = lbHandler.SessionMgr
+	apiService.Router = lbHandler.L7Router
+	if cfg.ProxyProtocol {
+		serverManager.SetProxyProtocol(true)
+		log.Info("PROXY protocol parsing enabled on the load balancer listener")
+	}
+	if cfg.ServerTimeouts.KeepAlivePeriodSeconds > 0 {
+		serverManager.SetKeepAlivePeriod(time.Duration(cfg.ServerTimeouts.KeepAlivePeriodSeconds) * time.Second)
+	}
 
 	log.Info("Starting servers",
 		zap.Int("lbPort", cfg.LoadBalancerPort),
@@ -124,6 +296,19 @@ func main() {
 		zap.Int("lbPort", cfg.LoadBalancerPort),
 		zap.Int("apiPort", cfg.ApiPort))
 
+	// Config reload on SIGHUP; a missing or broken file keeps the old config
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := cfg.Reload(configPath); err != nil {
+				log.Error("Config reload failed, keeping previous configuration", zap.Error(err))
+				continue
+			}
+			log.Info("Config reloaded", zap.String("path", configPath))
+		}
+	}()
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -2,40 +2,302 @@ package config
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type BackendServerConfig struct {
-	ID     string `yaml:"id"`
-	URL    string `yaml:"url"`
-	Weight int    `yaml:"weight,omitempty"`
-	// Add health check specific configs here later
+	ID              string            `yaml:"id"`
+	URL             string            `yaml:"url"`
+	Weight          float64           `yaml:"weight,omitempty"`          // Fractional weights like 1.5 are allowed; integers still work
+	Tags            map[string]string `yaml:"tags,omitempty"`            // Free-form labels for grouping (e.g. region, canary)
+	HealthCheckPort int               `yaml:"healthCheckPort,omitempty"` // Probe this port instead of the service port
+	HealthCheckURL  string            `yaml:"healthCheckUrl,omitempty"`  // Full health endpoint override; takes precedence over the port
+	ProxyProtocol   string            `yaml:"proxyProtocol,omitempty"`   // "v1" or "v2" to send a PROXY header to this backend
+	Zone            string            `yaml:"zone,omitempty"`            // Availability zone, for same-zone routing preference
+}
+
+// ScaleWeights converts possibly fractional weights into integer weights that
+// preserve their ratios, so fractional config values like 1.5:1 become 15:10.
+// Weights are resolved to at most three decimal places.
+func ScaleWeights(weights []float64) []int {
+	scale := 1
+	for scale < 1000 {
+		exact := true
+		for _, w := range weights {
+			scaled := w * float64(scale)
+			if math.Abs(scaled-math.Round(scaled)) > 1e-9 {
+				exact = false
+				break
+			}
+		}
+		if exact {
+			break
+		}
+		scale *= 10
+	}
+
+	result := make([]int, len(weights))
+	for i, w := range weights {
+		result[i] = int(math.Round(w * float64(scale)))
+	}
+	return result
+}
+
+type LogSamplingConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	First      int  `yaml:"first,omitempty"`      // Entries logged per second before sampling kicks in
+	Thereafter int  `yaml:"thereafter,omitempty"` // After that, log every Nth entry
+}
+
+type LogFileConfig struct {
+	Path       string `yaml:"path"`                 // Log file path; empty disables file output
+	MaxSizeMB  int    `yaml:"maxSizeMB,omitempty"`  // Rotate after the file reaches this size
+	MaxBackups int    `yaml:"maxBackups,omitempty"` // Number of rotated files to keep
+	MaxAgeDays int    `yaml:"maxAgeDays,omitempty"` // Delete rotated files older than this
+}
+
+// AdaptiveScoringConfig tunes the AdaptiveAlgorithm's score blend. Zero
+// values fall back to the algorithm's balanced defaults.
+type AdaptiveScoringConfig struct {
+	ConnectionWeight float64 `yaml:"connectionWeight,omitempty"` // Importance of active connections
+	LatencyWeight    float64 `yaml:"latencyWeight,omitempty"`    // Importance of EWMA latency
+}
+
+// ErrorEjectionConfig ejects backends whose rolling 5xx rate is too high.
+type ErrorEjectionConfig struct {
+	Threshold     float64 `yaml:"threshold,omitempty"`     // Error fraction (0..1) above which a backend is ejected
+	WindowSeconds int     `yaml:"windowSeconds,omitempty"` // Rolling window; default 30
+	MinSamples    int     `yaml:"minSamples,omitempty"`    // Minimum responses in the window before ejecting; default 10
+}
+
+// StickyRebalanceConfig gradually re-pins sticky sessions onto newly added
+// backends, so new capacity isn't starved until existing sessions expire.
+type StickyRebalanceConfig struct {
+	Fraction      float64 `yaml:"fraction,omitempty"`      // Chance a sticky hit is re-pinned while a new backend exists; 0 disables
+	WindowSeconds int     `yaml:"windowSeconds,omitempty"` // How long after being added a backend counts as new; default 300
+}
+
+// ProxyErrorConfig customizes the JSON error returned when a backend request
+// fails. Zero values keep the built-in 502 response.
+type ProxyErrorConfig struct {
+	StatusCode int    `yaml:"statusCode,omitempty"` // Response status for proxy failures
+	Message    string `yaml:"message,omitempty"`    // Client-facing message; {backendId} is substituted
+}
+
+// ServerTimeoutsConfig tunes inbound connection handling on the HTTP servers.
+type ServerTimeoutsConfig struct {
+	IdleTimeoutSeconds       int `yaml:"idleTimeoutSeconds,omitempty"`       // Keep-alive idle timeout; 0 keeps the built-in default
+	ReadHeaderTimeoutSeconds int `yaml:"readHeaderTimeoutSeconds,omitempty"` // Time allowed to read request headers
+	KeepAlivePeriodSeconds   int `yaml:"keepAlivePeriodSeconds,omitempty"`   // TCP keep-alive probe period on accepted connections
+	MaxHeaderBytes           int `yaml:"maxHeaderBytes,omitempty"`           // Cap on request header size; 0 keeps Go's 1MB default
+}
+
+// IPFilterRuleConfig is one entry in the ordered IP filter rule list.
+type IPFilterRuleConfig struct {
+	CIDR   string `yaml:"cidr"`   // CIDR or bare IP
+	Action string `yaml:"action"` // allow or deny
+}
+
+// IPFilterConfig configures client IP filtering with an explicit default
+// policy; rules are evaluated in list order, first match wins.
+type IPFilterConfig struct {
+	Enabled       bool                 `yaml:"enabled"`
+	DefaultPolicy string               `yaml:"defaultPolicy,omitempty"` // allow (default) or deny
+	Rules         []IPFilterRuleConfig `yaml:"rules,omitempty"`
+}
+
+// LoadSheddingConfig protects the balancer under system overload by shedding
+// a growing fraction of requests once usage crosses the thresholds.
+type LoadSheddingConfig struct {
+	Enabled                bool    `yaml:"enabled"`
+	CPUThresholdPercent    float64 `yaml:"cpuThresholdPercent,omitempty"`    // Shedding starts above this CPU usage; default 85
+	MemoryThresholdPercent float64 `yaml:"memoryThresholdPercent,omitempty"` // Shedding starts above this memory usage; default 90
+	SampleIntervalSeconds  int     `yaml:"sampleIntervalSeconds,omitempty"`  // How often usage is sampled; default 5
+}
+
+// MaintenanceConfig puts the whole load balancer into maintenance mode,
+// returning 503 with a Retry-After for everything but allowlisted paths.
+type MaintenanceConfig struct {
+	Enabled           bool     `yaml:"enabled"`
+	RetryAfterSeconds int      `yaml:"retryAfterSeconds,omitempty"` // Advertised in the Retry-After header
+	Message           string   `yaml:"message,omitempty"`           // Client-facing message in the 503 body
+	AllowedPaths      []string `yaml:"allowedPaths,omitempty"`      // Paths still served during maintenance
+}
+
+// HealthCheckAuthConfig carries credentials for probing protected health
+// endpoints. A bearer token wins over basic auth when both are set.
+type HealthCheckAuthConfig struct {
+	BearerToken string `yaml:"bearerToken,omitempty"` // Sent as "Authorization: Bearer <token>"
+	Username    string `yaml:"username,omitempty"`    // Basic auth user
+	Password    string `yaml:"password,omitempty"`    // Basic auth password
 }
 
 type HealthCheckConfig struct {
-	IntervalSeconds int    `yaml:"intervalSeconds"`
-	TimeoutSeconds  int    `yaml:"timeoutSeconds"`
-	Path            string `yaml:"path,omitempty"` // For HTTP checks
+	IntervalSeconds    int    `yaml:"intervalSeconds"`
+	TimeoutSeconds     int    `yaml:"timeoutSeconds"`
+	Path               string `yaml:"path,omitempty"`               // For HTTP checks
+	HTTPSPath          string `yaml:"httpsPath,omitempty"`          // Overrides Path for HTTPS backends
+	HealthyThreshold   int    `yaml:"healthyThreshold,omitempty"`   // Consecutive successes before HEALTHY; 0 means 1
+	CooldownSeconds    int    `yaml:"cooldownSeconds,omitempty"`    // Minimum time UNHEALTHY before rejoining rotation
+	UnhealthyThreshold int    `yaml:"unhealthyThreshold,omitempty"` // Consecutive failures before UNHEALTHY; 0 means 1
+	ExpectBody         string `yaml:"expectBody,omitempty"`         // Substring required in the HTTP response body
+	TCPSend            string `yaml:"tcpSend,omitempty"`            // Payload sent after a TCP connect (banner checks)
+	TCPExpect          string `yaml:"tcpExpect,omitempty"`          // Substring required in the TCP response
+	RedirectPolicy     string `yaml:"redirectPolicy,omitempty"`     // "deny" (default) surfaces redirects as failures; "follow" chases them
+	Composite          string `yaml:"composite,omitempty"`          // "all" requires TCP connect and HTTP 200 both; "any" either; empty disables
+	SkipInitialCheck   bool   `yaml:"skipInitialCheck,omitempty"`   // Skip the immediate startup pass and wait for the first interval
+	ForceChecker       string `yaml:"forceChecker,omitempty"`       // Force a checker type ("http", "tcp", "tls", or a registered custom type) regardless of backend scheme
+	TLSSkipVerify      bool   `yaml:"tlsSkipVerify,omitempty"`      // Skip certificate verification for TLS handshake checks
+	MaxRedirects       int    `yaml:"maxRedirects,omitempty"`       // Hop limit when following; 0 uses Go's default of 10
+
+	Auth HealthCheckAuthConfig `yaml:"auth,omitempty"` // Credentials sent with HTTP health probes
+}
+
+// UpstreamConnectionsConfig caps the shared upstream transport's connection
+// pools, so a single backend can't exhaust local sockets. Zero values keep
+// Go's defaults (unlimited for maxConnsPerHost).
+type UpstreamConnectionsConfig struct {
+	MaxIdleConns        int `yaml:"maxIdleConns,omitempty"`        // Idle connections across all backends
+	MaxIdleConnsPerHost int `yaml:"maxIdleConnsPerHost,omitempty"` // Idle connections per backend
+	MaxConnsPerHost     int `yaml:"maxConnsPerHost,omitempty"`     // Total connections per backend, idle or active
+
+	IdleConnTimeoutSeconds int `yaml:"idleConnTimeoutSeconds,omitempty"` // Close idle keep-alive connections after this; 0 keeps Go's 90s default
 }
 
 type Config struct {
-	LoadBalancerPort       int                   `yaml:"loadBalancerPort"`
-	ApiPort                int                   `yaml:"apiPort"`
-	APIKey                 string                `yaml:"apiKey"`
-	LoadBalancingAlgorithm string                `yaml:"loadBalancingAlgorithm"`
-	SSLCertPath            string                `yaml:"sslCertPath"`
-	SSLKeyPath             string                `yaml:"sslKeyPath"`
-	BackendServers         []BackendServerConfig `yaml:"backendServers"`
-	HealthCheck            HealthCheckConfig     `yaml:"healthCheck"`
+	LoadBalancerPort       int                       `yaml:"loadBalancerPort"`
+	ApiPort                int                       `yaml:"apiPort"`
+	ApiRequestTimeoutSecs  int                       `yaml:"apiRequestTimeoutSeconds,omitempty"`   // Per-request handler timeout for the API server
+	GeoSampleRate          int                       `yaml:"geoSampleRate,omitempty"`              // Track 1 in N requests for geographic stats
+	LogLevel               string                    `yaml:"logLevel,omitempty"`                   // debug, info, warn, error
+	LogFormat              string                    `yaml:"logFormat,omitempty"`                  // json or console
+	LogSampling            LogSamplingConfig         `yaml:"logSampling,omitempty"`                // Access-log sampling for the load balancer
+	LogFile                LogFileConfig             `yaml:"logFile,omitempty"`                    // Rotating file output alongside stdout
+	WarmUpConnections      int                       `yaml:"warmUpConnections,omitempty"`          // Idle connections to open when a backend is added; 0 disables
+	MaxInFlightRequests    int                       `yaml:"maxInFlightRequests,omitempty"`        // Global cap on concurrently proxied requests; 0 means unlimited
+	UpstreamTimeoutSecs    int                       `yaml:"upstreamTimeoutSeconds,omitempty"`     // Global upstream response timeout; routing rules may override it
+	ShutdownGraceSecs      int                       `yaml:"shutdownGracePeriodSeconds,omitempty"` // How long in-flight requests may finish during shutdown; 0 uses the default
+	MaxBackends            int                       `yaml:"maxBackends,omitempty"`                // Maximum number of backends in the pool; 0 means unlimited
+	ServerTimeouts         ServerTimeoutsConfig      `yaml:"serverTimeouts,omitempty"`             // Inbound keep-alive and header timeout tuning
+	ProxyError             ProxyErrorConfig          `yaml:"proxyError,omitempty"`                 // Custom proxy failure response
+	StripResponseHeaders   []string                  `yaml:"stripResponseHeaders,omitempty"`       // Response headers removed from proxied responses; empty keeps defaults
+	StickyRebalance        StickyRebalanceConfig     `yaml:"stickyRebalance,omitempty"`            // Gradual sticky-session rebalancing onto new backends
+	Zone                   string                    `yaml:"zone,omitempty"`                       // This load balancer's zone; same-zone backends are preferred when set
+	RequestCoalescing      bool                      `yaml:"requestCoalescing,omitempty"`          // Share one upstream call among identical concurrent GETs
+	ErrorEjection          ErrorEjectionConfig       `yaml:"errorEjection,omitempty"`              // Eject backends on excessive 5xx rates
+	UpstreamConnections    UpstreamConnectionsConfig `yaml:"upstreamConnections,omitempty"`        // Connection pool caps for the shared upstream transport
+	AdaptiveScoring        AdaptiveScoringConfig     `yaml:"adaptiveScoring,omitempty"`            // Score weights for the adaptive algorithm
+	APIKey                 string                    `yaml:"apiKey" json:"-"`                      // Never serialized to API clients or logs
+	APIAuthEnabled         *bool                     `yaml:"apiAuthEnabled,omitempty"`             // Pointer so an absent key defaults to enabled
+	APIKeyHeader           string                    `yaml:"apiKeyHeader,omitempty"`               // Extra header accepted for the API key (e.g. X-API-Key)
+	LoadBalancingAlgorithm string                    `yaml:"loadBalancingAlgorithm"`
+	SSLCertPath            string                    `yaml:"sslCertPath"`
+	SSLKeyPath             string                    `yaml:"sslKeyPath"`
+	BackendServers         []BackendServerConfig     `yaml:"backendServers"`
+	HealthCheck            HealthCheckConfig         `yaml:"healthCheck"`
+	Maintenance            MaintenanceConfig         `yaml:"maintenance,omitempty"`
+	IPFilter               IPFilterConfig            `yaml:"ipFilter,omitempty"`
+	LoadShedding           LoadSheddingConfig        `yaml:"loadShedding,omitempty"`
+	ProxyProtocol          bool                      `yaml:"proxyProtocol,omitempty"` // Parse PROXY v1/v2 headers on inbound LB connections
 	// Add other config fields as you implement features (e.g., algorithms, SSL, rate limits)
 }
 
+// APIAuthIsEnabled reports whether API key authentication is on. It defaults
+// to enabled when apiAuthEnabled is not set in the config.
+func (c *Config) APIAuthIsEnabled() bool {
+	return c.APIAuthEnabled == nil || *c.APIAuthEnabled
+}
+
+// remoteConfigTimeout bounds fetching a remote config URL.
+const remoteConfigTimeout = 10 * time.Second
+
+// remoteConfigCachePath stores the last successfully fetched remote config,
+// used as a fallback when the remote source is unreachable.
+var remoteConfigCachePath = filepath.Join(os.TempDir(), "goloadbalancer-config-cache.yaml")
+
+// isRemoteConfigPath reports whether the config path points at an HTTP(S)
+// source rather than a local file.
+func isRemoteConfigPath(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// fetchRemoteConfig retrieves the config from a URL, caching it on success.
+// If the fetch fails, the last cached copy is returned instead so a config
+// service outage doesn't prevent startup.
+func fetchRemoteConfig(configURL string) ([]byte, error) {
+	client := &http.Client{Timeout: remoteConfigTimeout}
+	resp, err := client.Get(configURL)
+	if err == nil && resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err != nil {
+		if cached, cacheErr := os.ReadFile(remoteConfigCachePath); cacheErr == nil {
+			return cached, nil
+		}
+		return nil, fmt.Errorf("failed to fetch remote config from %s: %w", configURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote config body: %w", err)
+	}
+	// Best-effort cache write; a read-only filesystem shouldn't fail the load
+	_ = os.WriteFile(remoteConfigCachePath, data, 0644)
+	return data, nil
+}
+
+// expandEnvVars substitutes ${VAR} and $VAR references in the raw config with
+// values from the environment, so secrets like API keys don't have to be
+// committed. Referencing an unset variable is an error rather than a silent
+// empty string.
+func expandEnvVars(data []byte) ([]byte, error) {
+	var missing []string
+	expanded := os.Expand(string(data), func(name string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		missing = append(missing, name)
+		return ""
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("config references unset environment variables: %s", strings.Join(missing, ", "))
+	}
+	return []byte(expanded), nil
+}
+
+// LoadConfig reads the config from a local file path or, when the path is an
+// http(s) URL, from a remote source with a local cache fallback. ${VAR}
+// references in the config are expanded from the environment.
 func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+	var data []byte
+	var err error
+	if isRemoteConfigPath(path) {
+		data, err = fetchRemoteConfig(path)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	data, err = expandEnvVars(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
 	var cfg Config
@@ -50,6 +312,101 @@ func LoadConfig(path string) (*Config, error) {
 	if cfg.APIKey == "" {
 		cfg.APIKey = "dev_api_key_123" // Default fallback
 	}
+	if cfg.ApiRequestTimeoutSecs <= 0 {
+		cfg.ApiRequestTimeoutSecs = 30 // Default API request timeout
+	}
 
 	return &cfg, nil
 }
+
+// Validate checks the config for problems without applying it. It returns a
+// list of human-readable problems; an empty list means the config is valid.
+func (c *Config) Validate() []string {
+	var problems []string
+
+	validPort := func(p int) bool { return p > 0 && p <= 65535 }
+	if !validPort(c.LoadBalancerPort) {
+		problems = append(problems, fmt.Sprintf("loadBalancerPort must be between 1 and 65535, got %d", c.LoadBalancerPort))
+	}
+	if !validPort(c.ApiPort) {
+		problems = append(problems, fmt.Sprintf("apiPort must be between 1 and 65535, got %d", c.ApiPort))
+	}
+	if validPort(c.LoadBalancerPort) && c.LoadBalancerPort == c.ApiPort {
+		problems = append(problems, fmt.Sprintf("loadBalancerPort and apiPort must differ, both are %d", c.ApiPort))
+	}
+
+	switch c.LoadBalancingAlgorithm {
+	case "", "roundrobin", "leastconnections", "iphash", "weighted", "weightedrandom", "adaptive":
+	default:
+		problems = append(problems, fmt.Sprintf("unknown loadBalancingAlgorithm %q", c.LoadBalancingAlgorithm))
+	}
+
+	seenIDs := make(map[string]bool)
+	for i, server := range c.BackendServers {
+		if server.ID == "" {
+			problems = append(problems, fmt.Sprintf("backendServers[%d]: id is required", i))
+		} else if seenIDs[server.ID] {
+			problems = append(problems, fmt.Sprintf("backendServers[%d]: duplicate id %q", i, server.ID))
+		}
+		seenIDs[server.ID] = true
+
+		if server.URL == "" {
+			problems = append(problems, fmt.Sprintf("backendServers[%d]: url is required", i))
+		} else if u, err := url.Parse(server.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("backendServers[%d]: invalid url %q", i, server.URL))
+		}
+		if server.Weight < 0 {
+			problems = append(problems, fmt.Sprintf("backendServers[%d]: weight must not be negative, got %g", i, server.Weight))
+		}
+		switch server.ProxyProtocol {
+		case "", "v1", "v2":
+		default:
+			problems = append(problems, fmt.Sprintf("backendServers[%d]: proxyProtocol must be \"v1\" or \"v2\", got %q", i, server.ProxyProtocol))
+		}
+	}
+
+	if c.HealthCheck.IntervalSeconds <= 0 {
+		problems = append(problems, fmt.Sprintf("healthCheck.intervalSeconds must be positive, got %d", c.HealthCheck.IntervalSeconds))
+	}
+	if c.HealthCheck.TimeoutSeconds <= 0 {
+		problems = append(problems, fmt.Sprintf("healthCheck.timeoutSeconds must be positive, got %d", c.HealthCheck.TimeoutSeconds))
+	}
+
+	return problems
+}
+
+// runtimeMu guards the live Config against concurrent access: Reload rewrites
+// the whole struct on the SIGHUP goroutine while API handlers read and mutate
+// individual fields. A process has one live Config, so a package mutex keeps
+// the lock out of the struct itself (which Reload copies wholesale).
+var runtimeMu sync.RWMutex
+
+// RuntimeLock/RuntimeUnlock and RuntimeRLock/RuntimeRUnlock guard runtime
+// access to the live Config. Handlers that mutate fields take the write lock;
+// handlers that serialize or read fields that can change after startup take
+// the read lock. These are package functions rather than Config methods so
+// the struct stays freely copyable (Reload assigns it wholesale).
+func RuntimeLock()    { runtimeMu.Lock() }
+func RuntimeUnlock()  { runtimeMu.Unlock() }
+func RuntimeRLock()   { runtimeMu.RLock() }
+func RuntimeRUnlock() { runtimeMu.RUnlock() }
+
+// Reload re-reads the config file at path into the receiver. If the file is
+// missing or unreadable the previous in-memory config is kept untouched and a
+// descriptive error is returned, so a deleted config file never takes down a
+// running service. Fields that are mutable through the API at runtime (the
+// API key and the load balancing algorithm) keep their in-memory values: an
+// unrelated SIGHUP must not silently revert a rotated key or a switched
+// algorithm. Change those via the API, or restart, to pick them up from file.
+func (c *Config) Reload(path string) error {
+	newCfg, err := LoadConfig(path)
+	if err != nil {
+		return fmt.Errorf("config reload failed, keeping previous configuration: %w", err)
+	}
+	RuntimeLock()
+	defer RuntimeUnlock()
+	newCfg.APIKey = c.APIKey
+	newCfg.LoadBalancingAlgorithm = c.LoadBalancingAlgorithm
+	*c = *newCfg
+	return nil
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -51,3 +55,229 @@ func TestLoadConfig(t *testing.T) {
 		t.Errorf("expected APIKey 'testkey', got %q", cfg.APIKey)
 	}
 }
+
+func TestReloadKeepsConfigWhenFileDeleted(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config_test_*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+
+	if _, err := tmpfile.Write([]byte(testYAML)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	cfg, err := LoadConfig(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	// Delete the file, then attempt a reload
+	os.Remove(tmpfile.Name())
+	if err := cfg.Reload(tmpfile.Name()); err == nil {
+		t.Fatal("expected reload of deleted config to return an error")
+	}
+
+	// The prior in-memory config must be untouched
+	if cfg.LoadBalancerPort != 8080 {
+		t.Errorf("expected LoadBalancerPort 8080 after failed reload, got %d", cfg.LoadBalancerPort)
+	}
+	if len(cfg.BackendServers) != 2 {
+		t.Errorf("expected 2 backend servers after failed reload, got %d", len(cfg.BackendServers))
+	}
+}
+
+func TestScaleWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []float64
+		want    []int
+	}{
+		{"integers pass through", []float64{3, 2, 1}, []int{3, 2, 1}},
+		{"fractional 1.5:1 keeps 3:2 ratio", []float64{1.5, 1}, []int{15, 10}},
+		{"two decimal places", []float64{0.25, 1}, []int{25, 100}},
+	}
+
+	for _, tt := range tests {
+		got := ScaleWeights(tt.weights)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+				break
+			}
+		}
+	}
+}
+
+
+func TestLoadConfigFromURL(t *testing.T) {
+	origCache := remoteConfigCachePath
+	remoteConfigCachePath = filepath.Join(t.TempDir(), "cache.yaml")
+	defer func() { remoteConfigCachePath = origCache }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testYAML))
+	}))
+
+	cfg, err := LoadConfig(server.URL)
+	if err != nil {
+		t.Fatalf("LoadConfig from URL failed: %v", err)
+	}
+	if cfg.LoadBalancerPort != 8080 {
+		t.Errorf("expected LoadBalancerPort 8080, got %d", cfg.LoadBalancerPort)
+	}
+
+	// With the remote source down, the cached copy keeps loading working
+	server.Close()
+	cfg, err = LoadConfig(server.URL)
+	if err != nil {
+		t.Fatalf("LoadConfig fallback to cache failed: %v", err)
+	}
+	if cfg.LoadBalancerPort != 8080 {
+		t.Errorf("expected cached LoadBalancerPort 8080, got %d", cfg.LoadBalancerPort)
+	}
+}
+
+func TestLoadConfigFromUnreachableURLWithoutCache(t *testing.T) {
+	origCache := remoteConfigCachePath
+	remoteConfigCachePath = filepath.Join(t.TempDir(), "missing-cache.yaml")
+	defer func() { remoteConfigCachePath = origCache }()
+
+	if _, err := LoadConfig("http://127.0.0.1:1/config.yaml"); err == nil {
+		t.Error("expected an error when the remote source is down and no cache exists")
+	}
+}
+
+func TestLoadConfigParsesServerTimeouts(t *testing.T) {
+	yamlWithTimeouts := testYAML + `
+serverTimeouts:
+  idleTimeoutSeconds: 45
+  readHeaderTimeoutSeconds: 7
+  keepAlivePeriodSeconds: 60
+`
+	tmpfile, err := os.CreateTemp("", "config_timeouts_*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.Write([]byte(yamlWithTimeouts)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	cfg, err := LoadConfig(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.ServerTimeouts.IdleTimeoutSeconds != 45 {
+		t.Errorf("expected idleTimeoutSeconds 45, got %d", cfg.ServerTimeouts.IdleTimeoutSeconds)
+	}
+	if cfg.ServerTimeouts.ReadHeaderTimeoutSeconds != 7 {
+		t.Errorf("expected readHeaderTimeoutSeconds 7, got %d", cfg.ServerTimeouts.ReadHeaderTimeoutSeconds)
+	}
+	if cfg.ServerTimeouts.KeepAlivePeriodSeconds != 60 {
+		t.Errorf("expected keepAlivePeriodSeconds 60, got %d", cfg.ServerTimeouts.KeepAlivePeriodSeconds)
+	}
+}
+
+func TestLoadConfigExpandsEnvVars(t *testing.T) {
+	t.Setenv("TEST_LB_BACKEND_URL", "http://envhost:9009")
+	t.Setenv("TEST_LB_KEY", "from-env")
+
+	yamlWithEnv := `
+loadBalancerPort: 8080
+apiPort: 8081
+apiKey: ${TEST_LB_KEY}
+backendServers:
+  - id: "server1"
+    url: "${TEST_LB_BACKEND_URL}"
+    weight: 1
+healthCheck:
+  intervalSeconds: 5
+  timeoutSeconds: 2
+`
+	tmpfile, err := os.CreateTemp("", "config_env_*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.Write([]byte(yamlWithEnv)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	cfg, err := LoadConfig(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.APIKey != "from-env" {
+		t.Errorf("expected APIKey from environment, got %q", cfg.APIKey)
+	}
+	if cfg.BackendServers[0].URL != "http://envhost:9009" {
+		t.Errorf("expected substituted backend URL, got %q", cfg.BackendServers[0].URL)
+	}
+}
+
+func TestLoadConfigErrorsOnUnsetEnvVar(t *testing.T) {
+	yamlWithEnv := `
+loadBalancerPort: 8080
+apiPort: 8081
+apiKey: ${DEFINITELY_UNSET_LB_VAR_12345}
+healthCheck:
+  intervalSeconds: 5
+  timeoutSeconds: 2
+`
+	tmpfile, err := os.CreateTemp("", "config_env_missing_*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.Write([]byte(yamlWithEnv)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	_, err = LoadConfig(tmpfile.Name())
+	if err == nil {
+		t.Fatal("expected an error for an unset environment variable")
+	}
+	if !strings.Contains(err.Error(), "DEFINITELY_UNSET_LB_VAR_12345") {
+		t.Errorf("expected the missing variable name in the error, got: %v", err)
+	}
+}
+
+func TestReloadPreservesRuntimeMutatedFields(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config_test_*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(testYAML)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	cfg, err := LoadConfig(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	// Simulate runtime API changes, then reload the unchanged file
+	cfg.APIKey = "rotated-at-runtime-key"
+	cfg.LoadBalancingAlgorithm = "leastconnections"
+	if err := cfg.Reload(tmpfile.Name()); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if cfg.APIKey != "rotated-at-runtime-key" {
+		t.Errorf("expected reload to preserve the rotated API key, got %q", cfg.APIKey)
+	}
+	if cfg.LoadBalancingAlgorithm != "leastconnections" {
+		t.Errorf("expected reload to preserve the switched algorithm, got %q", cfg.LoadBalancingAlgorithm)
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxBufferedBodyBytes caps how much of a request body is buffered for
+// replay when no explicit limit is given.
+const DefaultMaxBufferedBodyBytes = 1 << 20 // 1 MiB
+
+// ReplayableBody holds a fully buffered request body that can be read any
+// number of times, for features that need to send the same body to more than
+// one destination (retries, request mirroring).
+type ReplayableBody struct {
+	data []byte
+}
+
+// Reader returns a fresh reader over the buffered body. Each call yields an
+// independent stream starting at the beginning.
+func (b *ReplayableBody) Reader() io.ReadCloser {
+	return io.NopCloser(bytes.NewReader(b.data))
+}
+
+// Len returns the buffered body size in bytes.
+func (b *ReplayableBody) Len() int {
+	return len(b.data)
+}
+
+// BufferRequestBody reads the request body into memory, up to limit bytes,
+// and replaces r.Body with a replayable copy so downstream handlers see the
+// request unchanged. Bodies larger than the limit are refused rather than
+// truncated, since a partial replay would corrupt the upstream request.
+// Chunked requests (ContentLength -1) get a concrete ContentLength once
+// buffered. A limit of zero or less uses DefaultMaxBufferedBodyBytes.
+func BufferRequestBody(r *http.Request, limit int64) (*ReplayableBody, error) {
+	if limit <= 0 {
+		limit = DefaultMaxBufferedBodyBytes
+	}
+	if r.Body == nil {
+		return &ReplayableBody{}, nil
+	}
+	if r.ContentLength > limit {
+		return nil, fmt.Errorf("request body of %d bytes exceeds the %d byte buffer limit", r.ContentLength, limit)
+	}
+
+	// Read one byte past the limit so chunked bodies that are too large are
+	// detected without buffering them whole.
+	data, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	_ = r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("buffering request body failed: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("request body exceeds the %d byte buffer limit", limit)
+	}
+
+	body := &ReplayableBody{data: data}
+	r.Body = body.Reader()
+	r.ContentLength = int64(len(data))
+	return body, nil
+}
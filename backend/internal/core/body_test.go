@@ -0,0 +1,78 @@
+package core
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBufferRequestBodyReplaysToMultipleDestinations(t *testing.T) {
+	const payload = `{"action":"retry-me"}`
+	req := httptest.NewRequest("POST", "/submit", strings.NewReader(payload))
+
+	body, err := BufferRequestBody(req, 1024)
+	if err != nil {
+		t.Fatalf("BufferRequestBody failed: %v", err)
+	}
+
+	// The original request still carries the full body
+	fromRequest, _ := io.ReadAll(req.Body)
+	if string(fromRequest) != payload {
+		t.Errorf("request body not preserved: got %q", fromRequest)
+	}
+	if req.ContentLength != int64(len(payload)) {
+		t.Errorf("expected ContentLength %d, got %d", len(payload), req.ContentLength)
+	}
+
+	// Two independent readers both see the complete body
+	first, _ := io.ReadAll(body.Reader())
+	second, _ := io.ReadAll(body.Reader())
+	if string(first) != payload || string(second) != payload {
+		t.Errorf("replayed bodies differ from the original: %q, %q", first, second)
+	}
+}
+
+func TestBufferRequestBodyHandlesChunkedEncoding(t *testing.T) {
+	const payload = "chunked body content"
+	req := httptest.NewRequest("POST", "/submit", strings.NewReader(payload))
+	req.ContentLength = -1 // As seen for chunked transfer encoding
+	req.TransferEncoding = []string{"chunked"}
+
+	body, err := BufferRequestBody(req, 1024)
+	if err != nil {
+		t.Fatalf("BufferRequestBody failed for a chunked request: %v", err)
+	}
+	if body.Len() != len(payload) {
+		t.Errorf("expected %d buffered bytes, got %d", len(payload), body.Len())
+	}
+	if req.ContentLength != int64(len(payload)) {
+		t.Errorf("expected a concrete ContentLength after buffering, got %d", req.ContentLength)
+	}
+}
+
+func TestBufferRequestBodyRefusesOversizedBodies(t *testing.T) {
+	req := httptest.NewRequest("POST", "/submit", strings.NewReader(strings.Repeat("x", 100)))
+	if _, err := BufferRequestBody(req, 50); err == nil {
+		t.Error("expected an error for a body over the declared limit")
+	}
+
+	// Chunked oversized bodies are caught too, despite the unknown length
+	req = httptest.NewRequest("POST", "/submit", strings.NewReader(strings.Repeat("x", 100)))
+	req.ContentLength = -1
+	if _, err := BufferRequestBody(req, 50); err == nil {
+		t.Error("expected an error for an oversized chunked body")
+	}
+}
+
+func TestBufferRequestBodyNilBody(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Body = nil
+	body, err := BufferRequestBody(req, 0)
+	if err != nil {
+		t.Fatalf("BufferRequestBody failed for a nil body: %v", err)
+	}
+	if body.Len() != 0 {
+		t.Errorf("expected an empty buffered body, got %d bytes", body.Len())
+	}
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+)
+
+// requestCoalescer deduplicates identical in-flight GETs: the first request
+// for a key proxies upstream while concurrent duplicates wait and replay the
+// captured response, so a thundering herd on one resource costs the backend a
+// single request.
+type requestCoalescer struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+// inflightCall is one leader request in progress; followers wait on done and
+// then read resp.
+type inflightCall struct {
+	done chan struct{}
+	resp *capturedResponse
+}
+
+// capturedResponse is a buffered upstream response, replayable to followers.
+type capturedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func newRequestCoalescer() *requestCoalescer {
+	return &requestCoalescer{calls: make(map[string]*inflightCall)}
+}
+
+// coalesceKey identifies interchangeable requests: same method, host, path,
+// and query.
+func coalesceKey(r *http.Request) string {
+	return r.Method + " " + r.Host + r.URL.Path + "?" + r.URL.RawQuery
+}
+
+// coalescable reports whether a request is safe to share a response with
+// identical peers: GETs without credentials or range reads. Anything carrying
+// per-client state must go upstream itself.
+func coalescable(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	return r.Header.Get("Authorization") == "" &&
+		r.Header.Get("Cookie") == "" &&
+		r.Header.Get("Range") == ""
+}
+
+// responseCapture buffers a handler's response so it can be written to the
+// leader's client and replayed to followers.
+type responseCapture struct {
+	header http.Header
+	buf    bytes.Buffer
+	status int
+}
+
+func newResponseCapture() *responseCapture {
+	return &responseCapture{header: make(http.Header), status: http.StatusOK}
+}
+
+func (rc *responseCapture) Header() http.Header { return rc.header }
+
+func (rc *responseCapture) WriteHeader(status int) { rc.status = status }
+
+func (rc *responseCapture) Write(p []byte) (int, error) { return rc.buf.Write(p) }
+
+// serve runs handler once per key: the leader proxies and every waiter gets a
+// copy of the captured response.
+func (c *requestCoalescer) serve(key string, w http.ResponseWriter, r *http.Request, handler http.Handler) {
+	c.mu.Lock()
+	if call, exists := c.calls[key]; exists {
+		c.mu.Unlock()
+		<-call.done
+		if call.resp != nil {
+			writeCaptured(w, call.resp)
+			return
+		}
+		// The leader never produced a response; fall through and go upstream.
+		handler.ServeHTTP(w, r)
+		return
+	}
+	call := &inflightCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	capture := newResponseCapture()
+	handler.ServeHTTP(capture, r)
+	call.resp = &capturedResponse{
+		status: capture.status,
+		header: capture.header,
+		body:   capture.buf.Bytes(),
+	}
+	writeCaptured(w, call.resp)
+}
+
+// writeCaptured replays a buffered response onto a live writer.
+func writeCaptured(w http.ResponseWriter, resp *capturedResponse) {
+	for name, values := range resp.header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// errorRateEjector tracks a rolling per-backend 5xx rate so backends that
+// answer connections but consistently fail can be taken out of rotation.
+// Recovery is left to the active health checks, which flip a backend back to
+// HEALTHY once probes pass again.
+type errorRateEjector struct {
+	mu         sync.Mutex
+	threshold  float64       // Error fraction (0..1) above which a backend is ejected
+	window     time.Duration // How far back samples count
+	minSamples int           // Minimum samples in the window before ejecting
+	samples    map[string][]errorSample
+}
+
+// errorSample is one proxied response outcome for a backend.
+type errorSample struct {
+	at      time.Time
+	isError bool
+}
+
+func newErrorRateEjector(threshold float64, window time.Duration, minSamples int) *errorRateEjector {
+	if minSamples <= 0 {
+		minSamples = 10
+	}
+	return &errorRateEjector{
+		threshold:  threshold,
+		window:     window,
+		minSamples: minSamples,
+		samples:    make(map[string][]errorSample),
+	}
+}
+
+// record adds one response outcome and reports whether the backend's rolling
+// error rate now exceeds the threshold, along with the current rate.
+func (e *errorRateEjector) record(backendID string, isError bool) (eject bool, rate float64) {
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	window := append(e.samples[backendID], errorSample{at: now, isError: isError})
+	// Prune samples that fell out of the window
+	cutoff := now.Add(-e.window)
+	start := 0
+	for start < len(window) && window[start].at.Before(cutoff) {
+		start++
+	}
+	window = window[start:]
+	e.samples[backendID] = window
+
+	if len(window) < e.minSamples {
+		return false, 0
+	}
+	errors := 0
+	for _, sample := range window {
+		if sample.isError {
+			errors++
+		}
+	}
+	rate = float64(errors) / float64(len(window))
+	return rate > e.threshold, rate
+}
+
+// forget drops the accumulated samples for a backend, so a freshly recovered
+// backend starts from a clean slate instead of its pre-ejection history.
+func (e *errorRateEjector) forget(backendID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.samples, backendID)
+}
@@ -1,14 +1,27 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httputil"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/routing"
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/session"
+	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/httputils"
 )
 
 // LoadBalancer is the core load balancing service handler.
@@ -17,27 +30,366 @@ type LoadBalancer struct {
 	Logger     *zap.Logger
 	SessionMgr *session.SessionManager
 	L7Router   *routing.L7Router
+	accessLog  *zap.Logger // Per-request logger; may be sampled. Errors use Logger directly.
+
+	maxInFlight int64 // Global concurrency cap; 0 means unlimited
+	draining    int32 // Non-zero once shutdown draining has begun
+
+	shedRateBits uint64 // Current shed probability as float64 bits; 0 disables
+
+	shedCPUThreshold float64        // CPU percent above which shedding starts
+	shedMemThreshold float64        // Memory percent above which shedding starts
+	shedCPUUsage     func() float64 // Current CPU usage percent
+	shedMemUsage     func() float64 // Current memory usage percent
+
+	maintenance           int32    // Non-zero while global maintenance mode is on
+	maintenanceRetryAfter int      // Retry-After seconds advertised during maintenance
+	maintenanceMessage    string   // Client-facing maintenance message
+	maintenanceAllowed    []string // Paths still served during maintenance
+
+	upstreamTimeout time.Duration // Global upstream response timeout; 0 disables
+
+	requireRouteMatch bool // Reject unrouted requests with 404 instead of using the base pool
+
+	transport http.RoundTripper // Shared upstream transport; nil uses http.DefaultTransport
+
+	proxyErrorStatus  int    // Status code for proxy failures; defaults to 502
+	proxyErrorMessage string // Client-facing message; {backendId} is substituted
+
+	stripHeaders []string // Response headers removed before reaching the client
+
+	coalescer *requestCoalescer // Single-flight for identical GETs; nil disables
+
+	ejector *errorRateEjector // Ejects backends whose rolling 5xx rate is too high; nil disables
 }
 
 // NewLoadBalancer creates a new LoadBalancer instance.
 func NewLoadBalancer(pool *loadbalancing.ServerPool, log *zap.Logger) *LoadBalancer {
+	sessionMgr := session.NewSessionManager()
+	sessionMgr.SetLogger(log)
 	return &LoadBalancer{
 		Pool:       pool,
 		Logger:     log,
-		SessionMgr: session.NewSessionManager(),
+		SessionMgr: sessionMgr,
 		L7Router:   nil, // Set externally if needed
+		accessLog:  log,
+
+		proxyErrorStatus:  http.StatusBadGateway,
+		proxyErrorMessage: "upstream backend request failed",
+
+		maintenanceRetryAfter: 300,
+		maintenanceMessage:    "service is undergoing scheduled maintenance",
+		maintenanceAllowed:    []string{"/health", "/healthz", "/ping"},
+
+		stripHeaders: defaultStripHeaders(),
+	}
+}
+
+// defaultStripHeaders is the safe default set of response headers removed from
+// proxied responses: they advertise backend implementation details without
+// serving clients.
+func defaultStripHeaders() []string {
+	return []string{"Server", "X-Powered-By", "X-AspNet-Version", "X-Runtime"}
+}
+
+// ConfigureErrorRateEjection ejects backends whose rolling 5xx fraction over
+// the window exceeds the threshold (0..1), once at least minSamples responses
+// have been observed. Ejected backends are marked UNHEALTHY and come back via
+// the active health checks.
+func (lb *LoadBalancer) ConfigureErrorRateEjection(threshold float64, window time.Duration, minSamples int) {
+	lb.ejector = newErrorRateEjector(threshold, window, minSamples)
+}
+
+// recordBackendResult feeds one proxied response outcome into the error-rate
+// ejector and ejects the backend when it crosses the threshold.
+func (lb *LoadBalancer) recordBackendResult(pool *loadbalancing.ServerPool, backendID string, isError bool) {
+	if lb.ejector == nil {
+		return
+	}
+	eject, rate := lb.ejector.record(backendID, isError)
+	if !eject {
+		return
+	}
+	lb.ejector.forget(backendID)
+	lb.Logger.Warn("Ejecting backend for excessive error rate",
+		zap.String("backend_id", backendID),
+		zap.Float64("error_rate", rate))
+	pool.SetBackendStatusWithReason(backendID, model.UNHEALTHY,
+		fmt.Sprintf("error rate %.0f%% exceeded threshold", rate*100))
+}
+
+// EnableRequestCoalescing turns on single-flight handling of identical
+// concurrent GETs: duplicates of an in-flight request share one upstream call
+// and all receive the captured response.
+func (lb *LoadBalancer) EnableRequestCoalescing() {
+	lb.coalescer = newRequestCoalescer()
+}
+
+// SetStripResponseHeaders overrides which response headers are removed from
+// proxied responses before they reach the client. An empty list disables
+// stripping entirely.
+func (lb *LoadBalancer) SetStripResponseHeaders(headers []string) {
+	lb.stripHeaders = headers
+}
+
+// SetProxyErrorResponse overrides the status code and message returned to
+// clients when a backend request fails. The message may contain {backendId},
+// which is replaced with the failed backend's ID.
+func (lb *LoadBalancer) SetProxyErrorResponse(status int, message string) {
+	if status > 0 {
+		lb.proxyErrorStatus = status
+	}
+	if message != "" {
+		lb.proxyErrorMessage = message
+	}
+}
+
+// respondProxyError writes the structured JSON error payload for a failed
+// proxied request. Backend IDs are included; internal URLs are not.
+func (lb *LoadBalancer) respondProxyError(rw http.ResponseWriter, req *http.Request, status int, message, backendID string) {
+	httputils.RespondJSON(rw, status, httputils.ErrorResponse{
+		Error:      strings.ReplaceAll(message, "{backendId}", backendID),
+		Message:    http.StatusText(status),
+		StatusCode: status,
+		Timestamp:  time.Now(),
+		RequestID:  req.Header.Get("X-Request-ID"),
+		Details:    map[string]interface{}{"backendId": backendID},
+	})
+}
+
+// ConfigureLoadShedding enables overload protection: when the sampled CPU or
+// memory usage exceeds its threshold (percent), a fraction of new requests is
+// rejected with 503, scaling linearly from 0 at the threshold to everything
+// at 100%. The usage functions are sampled by StartLoadShedding.
+func (lb *LoadBalancer) ConfigureLoadShedding(cpuThreshold, memThreshold float64, cpuUsage, memUsage func() float64) {
+	lb.shedCPUThreshold = cpuThreshold
+	lb.shedMemThreshold = memThreshold
+	lb.shedCPUUsage = cpuUsage
+	lb.shedMemUsage = memUsage
+}
+
+// StartLoadShedding samples system usage on the given interval and adjusts
+// the shed rate. Call after ConfigureLoadShedding.
+func (lb *LoadBalancer) StartLoadShedding(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			lb.updateShedRate()
+		}
+	}()
+}
+
+// overloadFraction maps a usage reading to a 0..1 shed fraction for one
+// resource: 0 at or below the threshold, 1 at full saturation.
+func overloadFraction(usage, threshold float64) float64 {
+	if threshold <= 0 || usage <= threshold || threshold >= 100 {
+		return 0
+	}
+	frac := (usage - threshold) / (100 - threshold)
+	if frac > 1 {
+		return 1
+	}
+	return frac
+}
+
+// updateShedRate recomputes the shed probability from current usage readings.
+func (lb *LoadBalancer) updateShedRate() {
+	rate := 0.0
+	if lb.shedCPUUsage != nil {
+		rate = overloadFraction(lb.shedCPUUsage(), lb.shedCPUThreshold)
+	}
+	if lb.shedMemUsage != nil {
+		if memRate := overloadFraction(lb.shedMemUsage(), lb.shedMemThreshold); memRate > rate {
+			rate = memRate
+		}
+	}
+	atomic.StoreUint64(&lb.shedRateBits, math.Float64bits(rate))
+}
+
+// ShedRate returns the current fraction of requests being shed (0 to 1).
+func (lb *LoadBalancer) ShedRate() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&lb.shedRateBits))
+}
+
+// SetMaintenanceMode toggles global maintenance mode. While on, all requests
+// outside the allowlisted paths get 503 with a Retry-After header and an
+// informative JSON body.
+func (lb *LoadBalancer) SetMaintenanceMode(on bool) {
+	if on {
+		atomic.StoreInt32(&lb.maintenance, 1)
+	} else {
+		atomic.StoreInt32(&lb.maintenance, 0)
+	}
+}
+
+// InMaintenanceMode reports whether global maintenance mode is on.
+func (lb *LoadBalancer) InMaintenanceMode() bool {
+	return atomic.LoadInt32(&lb.maintenance) != 0
+}
+
+// ConfigureMaintenanceResponse customizes the maintenance 503: the advertised
+// Retry-After, the client-facing message, and the paths that stay reachable.
+// Zero or empty arguments keep the corresponding default.
+func (lb *LoadBalancer) ConfigureMaintenanceResponse(retryAfterSecs int, message string, allowedPaths []string) {
+	if retryAfterSecs > 0 {
+		lb.maintenanceRetryAfter = retryAfterSecs
+	}
+	if message != "" {
+		lb.maintenanceMessage = message
+	}
+	if len(allowedPaths) > 0 {
+		lb.maintenanceAllowed = allowedPaths
+	}
+}
+
+// maintenanceAllows reports whether a path stays reachable during maintenance.
+func (lb *LoadBalancer) maintenanceAllows(path string) bool {
+	for _, allowed := range lb.maintenanceAllowed {
+		if path == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// BeginDrain puts the load balancer into drain mode: new requests are refused
+// with 503 while in-flight proxied requests run to completion.
+func (lb *LoadBalancer) BeginDrain() {
+	atomic.StoreInt32(&lb.draining, 1)
+}
+
+// ActiveConnections returns the number of in-flight proxied requests, summed
+// from the per-backend active-connection counters.
+func (lb *LoadBalancer) ActiveConnections() int64 {
+	var total int64
+	for _, srv := range lb.Pool.GetServers() {
+		total += srv.GetActiveConnections()
+	}
+	return total
+}
+
+// SetRequireRouteMatch controls what happens when L7 routing is enabled but
+// no rule (and no router default pool) matches a request: true returns 404 to
+// the client, false falls back to the base pool.
+func (lb *LoadBalancer) SetRequireRouteMatch(require bool) {
+	lb.requireRouteMatch = require
+}
+
+// SetUpstreamTimeout sets the global upstream response timeout. Routing rules
+// may override it per route; exceeding either returns 504 to the client.
+func (lb *LoadBalancer) SetUpstreamTimeout(d time.Duration) {
+	lb.upstreamTimeout = d
+}
+
+// ConfigureUpstreamTransport installs a shared upstream transport with the
+// given connection pool caps, so heavy traffic to one backend can't exhaust
+// local sockets. Zero values keep Go's defaults. Backends that opt into PROXY
+// protocol keep their per-request transports.
+func (lb *LoadBalancer) ConfigureUpstreamTransport(maxIdleConns, maxIdleConnsPerHost, maxConnsPerHost int) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if maxIdleConns > 0 {
+		transport.MaxIdleConns = maxIdleConns
+	}
+	if maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
 	}
+	if maxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = maxConnsPerHost
+	}
+	lb.transport = transport
+}
+
+// SetUpstreamIdleConnTimeout bounds how long idle keep-alive connections to
+// backends are kept before being closed, so the transport recycles
+// connections ahead of backends that drop them server-side. Installs the
+// shared transport if connection caps haven't already.
+func (lb *LoadBalancer) SetUpstreamIdleConnTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	transport, ok := lb.transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+		lb.transport = transport
+	}
+	transport.IdleConnTimeout = d
+}
+
+// SetMaxInFlightRequests caps the number of concurrently proxied requests.
+// Requests beyond the cap are rejected with 503 and a Retry-After header.
+// A value of zero or less removes the cap.
+func (lb *LoadBalancer) SetMaxInFlightRequests(n int) {
+	atomic.StoreInt64(&lb.maxInFlight, int64(n))
+}
+
+// ConfigureAccessLogSampling throttles the per-request log lines: within each
+// tick, the first N duplicate entries are logged and only every Mth after
+// that. Error logs are unaffected.
+func (lb *LoadBalancer) ConfigureAccessLogSampling(tick time.Duration, first, thereafter int) {
+	lb.accessLog = lb.Logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSampler(core, tick, first, thereafter)
+	}))
 }
 
 // ServeHTTP implements the http.Handler interface for the load balancer.
 func (lb *LoadBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	lb.Logger.Info("Incoming request", zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.String("remote_addr", r.RemoteAddr))
+	if lb.InMaintenanceMode() && !lb.maintenanceAllows(r.URL.Path) {
+		w.Header().Set("Retry-After", strconv.Itoa(lb.maintenanceRetryAfter))
+		httputils.RespondJSON(w, http.StatusServiceUnavailable, httputils.ErrorResponse{
+			Error:      lb.maintenanceMessage,
+			Message:    http.StatusText(http.StatusServiceUnavailable),
+			StatusCode: http.StatusServiceUnavailable,
+			Timestamp:  time.Now(),
+			RequestID:  r.Header.Get("X-Request-ID"),
+			Details:    map[string]interface{}{"maintenance": true, "retryAfterSeconds": lb.maintenanceRetryAfter},
+		})
+		return
+	}
+
+	if atomic.LoadInt32(&lb.draining) != 0 {
+		w.Header().Set("Connection", "close")
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	if rate := lb.ShedRate(); rate > 0 && rand.Float64() < rate {
+		lb.Logger.Warn("Shedding request due to system overload", zap.Float64("shed_rate", rate))
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	inFlight := lb.Pool.IncrementInFlight()
+	defer lb.Pool.DecrementInFlight()
+	if max := atomic.LoadInt64(&lb.maxInFlight); max > 0 && inFlight > max {
+		lb.Logger.Warn("Global concurrency cap reached, rejecting request",
+			zap.Int64("in_flight", inFlight),
+			zap.Int64("max_in_flight", max))
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	lb.accessLog.Info("Incoming request", zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.String("remote_addr", r.RemoteAddr))
 
-	// Layer 7 routing: select pool based on rules, else use default
+	// Layer 7 routing: select pool based on rules, else use default. A matched
+	// rule may also override the global upstream timeout.
 	pool := lb.Pool
+	timeout := lb.upstreamTimeout
 	if lb.L7Router != nil {
-		if routed := lb.L7Router.Route(r); routed != nil {
+		routed, rule := lb.L7Router.RouteRule(r)
+		switch {
+		case routed != nil:
 			pool = routed
+			if rule != nil && rule.Timeout > 0 {
+				timeout = rule.Timeout
+			}
+		case lb.requireRouteMatch:
+			lb.Logger.Warn("No routing rule matched request",
+				zap.String("host", r.Host),
+				zap.String("path", r.URL.Path))
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
 		}
 	}
 
@@ -47,14 +399,36 @@ func (lb *LoadBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		backend = pool.SelectBackend(r)
 		if backend != nil {
 			lb.SessionMgr.SetStickyServer(w, backend)
+			// Advance the Round Robin counter only when selection succeeded,
+			// so failed selections against an empty pool don't skew rotation.
+			pool.Next()
 		}
-		// Increment counter after successful backend selection for Round Robin
-		pool.Next()
 	}
 
 	if backend == nil {
-		lb.Logger.Error("No healthy backend servers available", zap.String("path", r.URL.Path))
-		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		cause := pool.EmptySelectionCause()
+		message := "no healthy backend servers available"
+		switch cause {
+		case loadbalancing.EmptyCauseNoBackends:
+			message = "no backend servers are configured"
+		case loadbalancing.EmptyCauseAllMaintenance:
+			message = "all backend servers are in maintenance mode"
+		case loadbalancing.EmptyCauseAllUnhealthy:
+			message = "all backend servers are unhealthy"
+		case loadbalancing.EmptyCauseAllDrained:
+			message = "all backend servers are drained (zero weight)"
+		}
+		lb.Logger.Error("No backend available for request",
+			zap.String("path", r.URL.Path),
+			zap.String("cause", cause))
+		httputils.RespondJSON(w, http.StatusServiceUnavailable, httputils.ErrorResponse{
+			Error:      message,
+			Message:    http.StatusText(http.StatusServiceUnavailable),
+			StatusCode: http.StatusServiceUnavailable,
+			Timestamp:  time.Now(),
+			RequestID:  r.Header.Get("X-Request-ID"),
+			Details:    map[string]interface{}{"cause": cause},
+		})
 		return
 	}
 
@@ -65,19 +439,56 @@ func (lb *LoadBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	backend.IncrementConnections()
 	defer backend.DecrementConnections() // Decrement when request is done
 
+	// Apply the effective upstream timeout, if any
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
 	// Create and execute a reverse proxy
 	proxy := httputil.NewSingleHostReverseProxy(backend.URL)
+	if lb.transport != nil {
+		proxy.Transport = lb.transport
+	}
+	if backend.ProxyProtocol != "" {
+		// The backend wants the original client address via PROXY protocol,
+		// so dial upstream through a transport that prefixes the header.
+		localAddr, _ := r.Context().Value(http.LocalAddrContextKey).(*net.TCPAddr)
+		proxy.Transport = proxyProtocolTransport(backend.ProxyProtocol, parseTCPAddr(r.RemoteAddr), localAddr)
+	}
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		for _, h := range lb.stripHeaders {
+			resp.Header.Del(h)
+		}
+		lb.recordBackendResult(pool, backend.ID, resp.StatusCode >= 500)
+		return nil
+	}
 	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
+		lb.recordBackendResult(pool, backend.ID, true)
+		if errors.Is(req.Context().Err(), context.DeadlineExceeded) {
+			lb.Logger.Error("Upstream timeout", zap.Error(err), zap.String("backend_id", backend.ID), zap.Duration("timeout", timeout))
+			lb.respondProxyError(rw, req, http.StatusGatewayTimeout, "upstream backend timed out", backend.ID)
+			return
+		}
 		lb.Logger.Error("Proxy error", zap.Error(err), zap.String("backend_id", backend.ID), zap.String("backend_url", backend.URL.String()))
-		http.Error(rw, "Bad Gateway", http.StatusBadGateway)
+		lb.respondProxyError(rw, req, lb.proxyErrorStatus, lb.proxyErrorMessage, backend.ID)
 	}
 
 	// Log the chosen backend
-	lb.Logger.Info("Routing request",
+	lb.accessLog.Info("Routing request",
 		zap.String("backend_id", backend.ID),
 		zap.String("backend_url", backend.URL.String()),
 		zap.Int64("active_connections", backend.GetActiveConnections()),
 	)
 
-	proxy.ServeHTTP(w, r)
+	// Feed real request latency into the backend's response-time stats so
+	// latency-aware metrics reflect actual traffic, not just probes.
+	start := time.Now()
+	if lb.coalescer != nil && coalescable(r) {
+		lb.coalescer.serve(coalesceKey(r), w, r, proxy)
+	} else {
+		proxy.ServeHTTP(w, r)
+	}
+	backend.SetResponseTime(time.Since(start))
 }
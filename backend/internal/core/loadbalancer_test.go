@@ -0,0 +1,833 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/routing"
+	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/httputils"
+)
+
+func TestAccessLogSamplingThrottlesRequestLogs(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	observed, recorded := observer.New(zap.InfoLevel)
+	logger := zap.New(observed)
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, logger)
+	// Log the first 2 identical entries per tick, then every 1000th
+	lb.ConfigureAccessLogSampling(time.Minute, 2, 1000)
+
+	const requests = 50
+	for i := 0; i < requests; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+	}
+
+	incoming := recorded.FilterMessage("Incoming request").Len()
+	if incoming >= requests {
+		t.Errorf("expected sampled access logs to be fewer than %d requests, got %d", requests, incoming)
+	}
+	if incoming == 0 {
+		t.Error("expected at least one access log entry")
+	}
+}
+
+func TestErrorLogsBypassSampling(t *testing.T) {
+	observed, recorded := observer.New(zap.InfoLevel)
+	logger := zap.New(observed)
+
+	// Pool with no backends so every request hits the error path
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	lb := NewLoadBalancer(pool, logger)
+	lb.ConfigureAccessLogSampling(time.Minute, 1, 1000)
+
+	const requests = 10
+	for i := 0; i < requests; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+	}
+
+	errors := recorded.FilterMessage("No backend available for request").Len()
+	if errors != requests {
+		t.Errorf("expected all %d error logs to pass through, got %d", requests, errors)
+	}
+}
+
+func TestMaxInFlightRequestsRejectsExcessWith503(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	lb.SetMaxInFlightRequests(2)
+
+	// Saturate the cap with two requests parked in the backend handler
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "/", nil)
+			lb.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+	<-started
+	<-started
+
+	// A third request must be shed immediately
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 when cap is reached, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header on shed requests")
+	}
+	if got := pool.GetInFlightRequests(); got != 2 {
+		t.Errorf("expected 2 in-flight requests while saturated, got %d", got)
+	}
+
+	release <- struct{}{}
+	release <- struct{}{}
+	wg.Wait()
+
+	if got := pool.GetInFlightRequests(); got != 0 {
+		t.Errorf("expected 0 in-flight requests after completion, got %d", got)
+	}
+}
+
+func TestPerRouteTimeoutOverridesGlobal(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	router := routing.NewL7Router()
+	router.Pools["slowpool"] = pool
+	router.Rules = []routing.RoutingRule{
+		{ID: "short", PathPrefix: "/short", TargetPoolID: "slowpool", Timeout: 50 * time.Millisecond},
+		{ID: "long", PathPrefix: "/long", TargetPoolID: "slowpool", Timeout: 2 * time.Second},
+	}
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	lb.L7Router = router
+
+	// The same backend latency times out on the short-timeout route
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/short", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status 504 on short-timeout route, got %d", w.Code)
+	}
+
+	// ...and succeeds on the long-timeout route
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/long", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 on long-timeout route, got %d", w.Code)
+	}
+}
+
+func TestProxiedRequestLatencyRecorded(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	if got := server.GetResponseTime(); got < delay.Milliseconds() {
+		t.Errorf("expected recorded response time of at least %dms, got %dms", delay.Milliseconds(), got)
+	}
+	if got := server.GetResponseTimeEWMA(); got < delay.Milliseconds() {
+		t.Errorf("expected EWMA of at least %dms after one request, got %dms", delay.Milliseconds(), got)
+	}
+}
+
+func TestProxyErrorReturnsStructuredJSON(t *testing.T) {
+	// Point at a port nothing listens on so the proxy fails immediately
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("dead-backend", "http://127.0.0.1:1", 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("expected status 502, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json response, got %q", ct)
+	}
+
+	var resp struct {
+		Error      string                 `json:"error"`
+		StatusCode int                    `json:"statusCode"`
+		RequestID  string                 `json:"requestId"`
+		Details    map[string]interface{} `json:"details"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected statusCode 502 in body, got %d", resp.StatusCode)
+	}
+	if resp.RequestID != "req-123" {
+		t.Errorf("expected requestId req-123, got %q", resp.RequestID)
+	}
+	if resp.Details["backendId"] != "dead-backend" {
+		t.Errorf("expected backendId dead-backend in details, got %v", resp.Details["backendId"])
+	}
+	if strings.Contains(resp.Error, "127.0.0.1") {
+		t.Error("proxy error must not leak internal backend URLs")
+	}
+}
+
+func TestProxyErrorResponseConfigurable(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("dead-backend", "http://127.0.0.1:1", 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	lb.SetProxyErrorResponse(http.StatusServiceUnavailable, "backend {backendId} is unavailable")
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected configured status 503, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "backend dead-backend is unavailable") {
+		t.Errorf("expected templated message in body, got %s", w.Body.String())
+	}
+}
+
+func TestMaintenanceModeReturns503WithAllowlist(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, _ := model.NewBackendServer("maint-backend", backend.URL, 1)
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+	lb := NewLoadBalancer(pool, zap.NewNop())
+
+	// Normal traffic flows before maintenance
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/app", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 before maintenance, got %d", w.Code)
+	}
+
+	lb.SetMaintenanceMode(true)
+
+	// Regular paths get a structured 503 with Retry-After
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/app", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 during maintenance, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header during maintenance")
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected a JSON maintenance body, got: %s", w.Body.String())
+	}
+	if body["error"] == "" {
+		t.Error("expected an informative error message in the maintenance body")
+	}
+
+	// Health endpoints stay reachable
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 for /health during maintenance, got %d", w.Code)
+	}
+
+	// And traffic resumes once maintenance is cleared
+	lb.SetMaintenanceMode(false)
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/app", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 after maintenance, got %d", w.Code)
+	}
+}
+
+func TestLoadSheddingRejectsUnderHighUsage(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, _ := model.NewBackendServer("shed-backend", backend.URL, 1)
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+	lb := NewLoadBalancer(pool, zap.NewNop())
+
+	// CPU pegged at 95% with an 80% threshold sheds 75% of requests
+	lb.ConfigureLoadShedding(80, 90,
+		func() float64 { return 95 },
+		func() float64 { return 50 })
+	lb.updateShedRate()
+	if rate := lb.ShedRate(); rate < 0.7 || rate > 0.8 {
+		t.Fatalf("expected shed rate around 0.75, got %v", rate)
+	}
+
+	shed := 0
+	for i := 0; i < 200; i++ {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest("GET", "/app", nil))
+		if w.Code == http.StatusServiceUnavailable {
+			shed++
+		}
+	}
+	if shed == 0 {
+		t.Error("expected some requests to be shed under overload")
+	}
+	if shed == 200 {
+		t.Error("expected some requests to still be served under partial overload")
+	}
+}
+
+func TestLoadSheddingInactiveUnderNormalUsage(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, _ := model.NewBackendServer("calm-backend", backend.URL, 1)
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+	lb := NewLoadBalancer(pool, zap.NewNop())
+
+	lb.ConfigureLoadShedding(80, 90,
+		func() float64 { return 40 },
+		func() float64 { return 60 })
+	lb.updateShedRate()
+	if rate := lb.ShedRate(); rate != 0 {
+		t.Fatalf("expected shed rate 0 under normal usage, got %v", rate)
+	}
+
+	for i := 0; i < 50; i++ {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest("GET", "/app", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected no shedding under normal usage, got %d", w.Code)
+		}
+	}
+}
+
+func TestEmptyPoolDoesNotAdvanceRotation(t *testing.T) {
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	lb := NewLoadBalancer(pool, zap.NewNop())
+
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503 from an empty pool, got %d", w.Code)
+		}
+	}
+
+	if got := pool.GetRotationCount(); got != 0 {
+		t.Errorf("expected rotation counter to stay at 0 for an empty pool, got %d", got)
+	}
+}
+
+func TestConfigureUpstreamTransportAppliesLimits(t *testing.T) {
+	lb := NewLoadBalancer(loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}), zap.NewNop())
+	lb.ConfigureUpstreamTransport(50, 5, 2)
+
+	transport, ok := lb.transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected *http.Transport, got %T", lb.transport)
+	}
+	if transport.MaxIdleConns != 50 || transport.MaxIdleConnsPerHost != 5 || transport.MaxConnsPerHost != 2 {
+		t.Errorf("unexpected limits: MaxIdleConns=%d MaxIdleConnsPerHost=%d MaxConnsPerHost=%d",
+			transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+}
+
+func TestSetUpstreamIdleConnTimeoutAppliedToTransport(t *testing.T) {
+	lb := NewLoadBalancer(loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}), zap.NewNop())
+
+	// Standalone: installs the shared transport with the timeout
+	lb.SetUpstreamIdleConnTimeout(45 * time.Second)
+	transport, ok := lb.transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected *http.Transport, got %T", lb.transport)
+	}
+	if transport.IdleConnTimeout != 45*time.Second {
+		t.Errorf("expected IdleConnTimeout of 45s, got %v", transport.IdleConnTimeout)
+	}
+
+	// On top of configured connection caps: same transport, both settings
+	lb = NewLoadBalancer(loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}), zap.NewNop())
+	lb.ConfigureUpstreamTransport(50, 5, 2)
+	lb.SetUpstreamIdleConnTimeout(30 * time.Second)
+	transport = lb.transport.(*http.Transport)
+	if transport.IdleConnTimeout != 30*time.Second {
+		t.Errorf("expected IdleConnTimeout of 30s, got %v", transport.IdleConnTimeout)
+	}
+	if transport.MaxIdleConns != 50 {
+		t.Errorf("expected connection caps preserved, got MaxIdleConns=%d", transport.MaxIdleConns)
+	}
+}
+
+func TestMaxConnsPerHostBoundsConcurrentUpstreamConnections(t *testing.T) {
+	var inFlight, peak int64
+	var mu sync.Mutex
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(30 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	lb.ConfigureUpstreamTransport(0, 0, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent upstream connections, observed %d", peak)
+	}
+}
+
+func TestStripResponseHeadersRemovedFromClientResponse(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "internal-app/1.2")
+		w.Header().Set("X-Powered-By", "Express")
+		w.Header().Set("X-Internal-Route", "cell-7")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	lb.SetStripResponseHeaders([]string{"Server", "X-Powered-By", "X-Internal-Route"})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	for _, h := range []string{"Server", "X-Powered-By", "X-Internal-Route"} {
+		if got := w.Header().Get(h); got != "" {
+			t.Errorf("expected header %s to be stripped, got %q", h, got)
+		}
+	}
+}
+
+func TestDefaultStripHeadersApplied(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Powered-By", "PHP/8.1")
+		w.Header().Set("X-App-Feature", "kept")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+
+	if got := w.Header().Get("X-Powered-By"); got != "" {
+		t.Errorf("expected default strip set to remove X-Powered-By, got %q", got)
+	}
+	if got := w.Header().Get("X-App-Feature"); got != "kept" {
+		t.Errorf("expected unrelated header to survive, got %q", got)
+	}
+}
+
+func TestRequireRouteMatchReturns404ForUnroutedRequests(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	apiPool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	apiPool.AddServer(server)
+
+	router := routing.NewL7Router()
+	router.Pools["api"] = apiPool
+	router.Rules = []routing.RoutingRule{{ID: "api", PathPrefix: "/api", TargetPoolID: "api"}}
+
+	lb := NewLoadBalancer(loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{}), zap.NewNop())
+	lb.L7Router = router
+	lb.SetRequireRouteMatch(true)
+
+	// Routed request reaches the pool's backend
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/api/users", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected routed request to reach the backend, got %d", w.Code)
+	}
+
+	// Unrouted request is rejected instead of hitting the empty base pool
+	w = httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/static/app.js", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unrouted request with strict matching, got %d", w.Code)
+	}
+}
+
+func TestRequestCoalescingSharesOneUpstreamCall(t *testing.T) {
+	var backendCalls int64
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&backendCalls, 1)
+		<-release // Hold the leader open so the others pile up behind it
+		w.Write([]byte("shared"))
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	lb.EnableRequestCoalescing()
+
+	const clients = 8
+	var wg sync.WaitGroup
+	bodies := make([]string, clients)
+	codes := make([]int, clients)
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			lb.ServeHTTP(w, httptest.NewRequest("GET", "/popular", nil))
+			bodies[i] = w.Body.String()
+			codes[i] = w.Code
+		}(i)
+	}
+
+	// Give the followers time to park behind the in-flight leader
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt64(&backendCalls) < 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt64(&backendCalls); got != 1 {
+		t.Errorf("expected the backend to see exactly 1 request, got %d", got)
+	}
+	for i := 0; i < clients; i++ {
+		if codes[i] != http.StatusOK || bodies[i] != "shared" {
+			t.Errorf("client %d got %d %q, want 200 \"shared\"", i, codes[i], bodies[i])
+		}
+	}
+}
+
+func TestRequestCoalescingSkipsCredentialedRequests(t *testing.T) {
+	var backendCalls int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&backendCalls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	lb.EnableRequestCoalescing()
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/account", nil)
+		req.Header.Set("Authorization", "Bearer user-token")
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+	}
+	if got := atomic.LoadInt64(&backendCalls); got != 3 {
+		t.Errorf("expected credentialed requests to bypass coalescing, backend saw %d of 3", got)
+	}
+}
+
+func TestHighErrorRateBackendIsEjected(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("flaky", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	lb.ConfigureErrorRateEjection(0.5, 10*time.Second, 5)
+
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	}
+
+	if server.HealthStatus != model.UNHEALTHY {
+		t.Errorf("expected an all-5xx backend to be ejected, status %s", server.HealthStatus)
+	}
+	history := server.GetHealthHistory()
+	if len(history) == 0 || history[len(history)-1].Reason == "" {
+		t.Error("expected the ejection to record a transition reason")
+	}
+}
+
+func TestLowErrorRateBackendStaysInRotation(t *testing.T) {
+	var calls int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// One failure in ten: well under the 50% threshold
+		if atomic.AddInt64(&calls, 1)%10 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("mostly-fine", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	lb.ConfigureErrorRateEjection(0.5, 10*time.Second, 5)
+
+	for i := 0; i < 30; i++ {
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	}
+
+	if server.HealthStatus != model.HEALTHY {
+		t.Errorf("expected a mostly-2xx backend to stay in rotation, status %s", server.HealthStatus)
+	}
+}
+
+func TestEmptySelection503DistinguishesCauses(t *testing.T) {
+	makeServer := func(t *testing.T, id, url string, status model.HealthStatus) *model.BackendServer {
+		t.Helper()
+		server, err := model.NewBackendServer(id, url, 1)
+		if err != nil {
+			t.Fatalf("failed to create backend server: %v", err)
+		}
+		server.SetStatus(status)
+		return server
+	}
+
+	cases := []struct {
+		name    string
+		algo    loadbalancing.LoadBalancingAlgorithm
+		setup   func(pool *loadbalancing.ServerPool)
+		cause   string
+		message string
+	}{
+		{
+			name:    "no backends configured",
+			setup:   func(pool *loadbalancing.ServerPool) {},
+			cause:   loadbalancing.EmptyCauseNoBackends,
+			message: "no backend servers are configured",
+		},
+		{
+			name: "all unhealthy",
+			setup: func(pool *loadbalancing.ServerPool) {
+				pool.AddServer(makeServer(t, "u1", "http://unhealthy-1:8080", model.UNHEALTHY))
+				pool.AddServer(makeServer(t, "u2", "http://unhealthy-2:8080", model.UNHEALTHY))
+			},
+			cause:   loadbalancing.EmptyCauseAllUnhealthy,
+			message: "all backend servers are unhealthy",
+		},
+		{
+			name: "all in maintenance",
+			setup: func(pool *loadbalancing.ServerPool) {
+				pool.AddServer(makeServer(t, "m1", "http://maint-1:8080", model.MAINTENANCE))
+				pool.AddServer(makeServer(t, "m2", "http://maint-2:8080", model.MAINTENANCE))
+			},
+			cause:   loadbalancing.EmptyCauseAllMaintenance,
+			message: "all backend servers are in maintenance mode",
+		},
+		{
+			name: "all drained",
+			algo: loadbalancing.NewWeightedRoundRobinAlgorithm(),
+			setup: func(pool *loadbalancing.ServerPool) {
+				drained := makeServer(t, "d1", "http://drained-1:8080", model.HEALTHY)
+				drained.Weight = 0
+				pool.AddServer(drained)
+			},
+			cause:   loadbalancing.EmptyCauseAllDrained,
+			message: "all backend servers are drained (zero weight)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			algo := tc.algo
+			if algo == nil {
+				algo = &loadbalancing.RoundRobinAlgorithm{}
+			}
+			pool := loadbalancing.NewServerPool(algo)
+			tc.setup(pool)
+			lb := NewLoadBalancer(pool, zap.NewNop())
+
+			w := httptest.NewRecorder()
+			lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			if w.Code != http.StatusServiceUnavailable {
+				t.Fatalf("expected 503, got %d", w.Code)
+			}
+			var resp httputils.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if resp.Error != tc.message {
+				t.Errorf("expected message %q, got %q", tc.message, resp.Error)
+			}
+			if got := resp.Details["cause"]; got != tc.cause {
+				t.Errorf("expected cause %q in details, got %v", tc.cause, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// proxyV2Signature opens every PROXY protocol v2 header, per the spec.
+var proxyV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// proxyProtocolTransport returns a RoundTripper that prefixes every upstream
+// connection with a PROXY protocol header carrying the original client
+// address, for backends that learn the client IP that way. version is "v1" or
+// "v2"; src is the downstream client address and dst the LB listener address
+// the client connected to (nil when unknown). The transport is built per
+// request (the header binds a connection to one client), so keep-alives are
+// disabled: an idle connection here could never be reused and would otherwise
+// just leak in this throwaway transport's pool.
+func proxyProtocolTransport(version string, src, dst *net.TCPAddr) http.RoundTripper {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	return &http.Transport{
+		DisableKeepAlives: true,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			headerDst := dst
+			if headerDst == nil {
+				headerDst = tcpAddr(conn.LocalAddr()) // Best effort when the listener address isn't known
+			}
+			header := encodeProxyHeader(version, src, headerDst)
+			if _, err := conn.Write(header); err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("writing PROXY header to backend: %w", err)
+			}
+			return conn, nil
+		},
+	}
+}
+
+// tcpAddr narrows a net.Addr to a TCP address, nil when it isn't one.
+func tcpAddr(addr net.Addr) *net.TCPAddr {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp
+	}
+	return nil
+}
+
+// parseTCPAddr parses a "host:port" string like http.Request.RemoteAddr into
+// a TCP address, nil when it doesn't parse.
+func parseTCPAddr(hostport string) *net.TCPAddr {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil
+	}
+	return &net.TCPAddr{IP: ip, Port: p}
+}
+
+// encodeProxyHeader builds the PROXY header for the given version. When the
+// addresses are missing or of mixed families it degrades to the spec's
+// "unknown" forms (v1 UNKNOWN, v2 LOCAL), which tell the backend to fall back
+// to the transport address rather than failing the connection.
+func encodeProxyHeader(version string, src, dst *net.TCPAddr) []byte {
+	if version == "v2" {
+		return encodeProxyV2Header(src, dst)
+	}
+	return encodeProxyV1Header(src, dst)
+}
+
+// encodeProxyV1Header renders the human-readable v1 form:
+// "PROXY TCP4 srcIP dstIP srcPort dstPort\r\n".
+func encodeProxyV1Header(src, dst *net.TCPAddr) []byte {
+	if src == nil || dst == nil || (src.IP.To4() == nil) != (dst.IP.To4() == nil) {
+		return []byte("PROXY UNKNOWN\r\n")
+	}
+	proto := "TCP6"
+	if src.IP.To4() != nil {
+		proto = "TCP4"
+	}
+	return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n", proto, src.IP.String(), dst.IP.String(), src.Port, dst.Port))
+}
+
+// encodeProxyV2Header renders the binary v2 form.
+func encodeProxyV2Header(src, dst *net.TCPAddr) []byte {
+	header := make([]byte, 0, 16+36)
+	header = append(header, proxyV2Signature...)
+	if src == nil || dst == nil || (src.IP.To4() == nil) != (dst.IP.To4() == nil) {
+		// LOCAL command, AF_UNSPEC: no address block.
+		return append(header, 0x20, 0x00, 0x00, 0x00)
+	}
+	if srcIP4 := src.IP.To4(); srcIP4 != nil {
+		header = append(header, 0x21, 0x11, 0x00, 12) // PROXY command, AF_INET/STREAM
+		header = append(header, srcIP4...)
+		header = append(header, dst.IP.To4()...)
+	} else {
+		header = append(header, 0x21, 0x21, 0x00, 36) // PROXY command, AF_INET6/STREAM
+		header = append(header, src.IP.To16()...)
+		header = append(header, dst.IP.To16()...)
+	}
+	header = append(header, byte(src.Port>>8), byte(src.Port))
+	header = append(header, byte(dst.Port>>8), byte(dst.Port))
+	return header
+}
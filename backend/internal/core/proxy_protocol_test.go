@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
+)
+
+// startProxyAwareBackend starts a raw TCP backend that parses a PROXY v1 or
+// v2 header off each connection and answers HTTP requests with the advertised
+// client address as the response body.
+func startProxyAwareBackend(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				client, err := readProxyHeaderForTest(reader)
+				if err != nil {
+					fmt.Fprintf(conn, "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n")
+					return
+				}
+				if _, err := http.ReadRequest(reader); err != nil {
+					return
+				}
+				fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s", len(client), client)
+			}(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// readProxyHeaderForTest consumes a PROXY v1 or v2 header and returns the
+// advertised client "ip:port".
+func readProxyHeaderForTest(r *bufio.Reader) (string, error) {
+	peek, err := r.Peek(12)
+	if err != nil {
+		return "", err
+	}
+	if string(peek) == string(proxyV2Signature) {
+		header := make([]byte, 16)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return "", err
+		}
+		addrs := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+		if _, err := io.ReadFull(r, addrs); err != nil {
+			return "", err
+		}
+		if header[13]>>4 != 0x1 || len(addrs) < 12 {
+			return "", fmt.Errorf("unexpected v2 address family %#x", header[13])
+		}
+		ip := net.IP(addrs[0:4])
+		port := binary.BigEndian.Uint16(addrs[8:10])
+		return fmt.Sprintf("%s:%d", ip, port), nil
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(strings.TrimRight(line, "\r\n"))
+	if len(fields) != 6 || fields[0] != "PROXY" {
+		return "", fmt.Errorf("malformed PROXY line: %q", line)
+	}
+	return fields[2] + ":" + fields[4], nil
+}
+
+func TestProxyProtocolV1SentToBackend(t *testing.T) {
+	addr := startProxyAwareBackend(t)
+	src := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 54321}
+	dst := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 80}
+	client := &http.Client{Transport: proxyProtocolTransport("v1", src, dst)}
+	resp, err := client.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("request through v1 transport failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "203.0.113.7:54321" {
+		t.Errorf("backend saw client %q, want 203.0.113.7:54321", body)
+	}
+}
+
+func TestProxyProtocolV2SentToBackend(t *testing.T) {
+	addr := startProxyAwareBackend(t)
+	src := &net.TCPAddr{IP: net.ParseIP("198.51.100.9"), Port: 4321}
+	dst := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 80}
+	client := &http.Client{Transport: proxyProtocolTransport("v2", src, dst)}
+	resp, err := client.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("request through v2 transport failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "198.51.100.9:4321" {
+		t.Errorf("backend saw client %q, want 198.51.100.9:4321", body)
+	}
+}
+
+func TestBackendProxyProtocolConveysClientIP(t *testing.T) {
+	addr := startProxyAwareBackend(t)
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("b1", "http://"+addr, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.ProxyProtocol = "v1"
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	lb := NewLoadBalancer(pool, zap.NewNop())
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.42:33000"
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 through the LB, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Body.String(); got != "203.0.113.42:33000" {
+		t.Errorf("backend saw client %q, want 203.0.113.42:33000", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 )
 
 // GeographicData represents geographic information for an IP address
@@ -33,14 +34,42 @@ type CountryStats struct {
 	Percentage  float64 `json:"percentage"`
 }
 
+// defaultGeoAPIBaseURL is the free ip-api.com JSON endpoint
+const defaultGeoAPIBaseURL = "http://ip-api.com/json"
+
+// lookupBackoffDuration is how long outbound lookups pause after the external
+// API rate-limits us with a 429.
+const lookupBackoffDuration = time.Minute
+
+// Transient failures are retried with a short backoff before the lookup is
+// given up on; the overall deadline keeps a flaky API from stalling a request.
+const (
+	maxLookupAttempts     = 3
+	lookupRetryBackoff    = 200 * time.Millisecond
+	overallLookupDeadline = 5 * time.Second
+)
+
+// defaultNegativeCacheTTL is how long a failed lookup is remembered so
+// repeated requests for the same IP don't hammer the external API. Kept much
+// shorter than successful entries, which live for the process lifetime.
+const defaultNegativeCacheTTL = 30 * time.Second
+
 // GeographicTracker handles IP geolocation and statistics
 type GeographicTracker struct {
-	cache      map[string]*GeographicData
-	stats      map[string]*CountryStats
-	cacheMutex sync.RWMutex
-	statsMutex sync.RWMutex
-	logger     *zap.Logger
-	httpClient *http.Client
+	cache         map[string]*GeographicData
+	stats         map[string]*CountryStats
+	cacheMutex    sync.RWMutex
+	statsMutex    sync.RWMutex
+	logger        *zap.Logger
+	httpClient    *http.Client
+	apiBaseURL    string
+	lookupLimiter *rate.Limiter
+	inflight      map[string]chan struct{} // Coalesces concurrent lookups for the same IP
+	inflightMutex sync.Mutex
+	negativeCache map[string]time.Time // IP -> expiry of a remembered failed lookup
+	negativeTTL   time.Duration
+	backoffUntil  time.Time // Outbound lookups are skipped until this time
+	backoffMutex  sync.RWMutex
 }
 
 // NewGeographicTracker creates a new geographic tracker
@@ -52,9 +81,30 @@ func NewGeographicTracker(logger *zap.Logger) *GeographicTracker {
 		httpClient: &http.Client{
 			Timeout: 5 * time.Second,
 		},
+		apiBaseURL: defaultGeoAPIBaseURL,
+		// ip-api.com's free tier allows ~45 requests/minute; stay under it
+		lookupLimiter: rate.NewLimiter(rate.Every(time.Minute/40), 5),
+		inflight:      make(map[string]chan struct{}),
+		negativeCache: make(map[string]time.Time),
+		negativeTTL:   defaultNegativeCacheTTL,
 	}
 }
 
+// SetNegativeCacheTTL overrides how long failed lookups are remembered (used in tests)
+func (gt *GeographicTracker) SetNegativeCacheTTL(ttl time.Duration) {
+	gt.negativeTTL = ttl
+}
+
+// SetLookupLimiter overrides the outbound lookup rate limiter (used in tests)
+func (gt *GeographicTracker) SetLookupLimiter(limiter *rate.Limiter) {
+	gt.lookupLimiter = limiter
+}
+
+// SetAPIBaseURL overrides the external geo API endpoint (used in tests)
+func (gt *GeographicTracker) SetAPIBaseURL(baseURL string) {
+	gt.apiBaseURL = baseURL
+}
+
 // ExtractClientIP extracts the real client IP from HTTP request headers
 func ExtractClientIP(r *http.Request) string {
 	// Check headers in order of reliability
@@ -154,31 +204,124 @@ func (gt *GeographicTracker) GetGeographicData(ip string) *GeographicData {
 		gt.cacheMutex.RUnlock()
 		return cached
 	}
+	// A recent failed lookup is remembered briefly; don't retry until it expires
+	if expiry, exists := gt.negativeCache[ip]; exists && time.Now().Before(expiry) {
+		gt.cacheMutex.RUnlock()
+		return nil
+	}
 	gt.cacheMutex.RUnlock()
 
+	// Coalesce concurrent lookups for the same IP: the first caller fetches,
+	// everyone else waits and reads the cached result.
+	gt.inflightMutex.Lock()
+	if pending, exists := gt.inflight[ip]; exists {
+		gt.inflightMutex.Unlock()
+		<-pending
+		gt.cacheMutex.RLock()
+		cached := gt.cache[ip]
+		gt.cacheMutex.RUnlock()
+		return cached
+	}
+	pending := make(chan struct{})
+	gt.inflight[ip] = pending
+	gt.inflightMutex.Unlock()
+
+	defer func() {
+		gt.inflightMutex.Lock()
+		delete(gt.inflight, ip)
+		gt.inflightMutex.Unlock()
+		close(pending)
+	}()
+
+	// Skip outbound lookups while backing off from a 429
+	gt.backoffMutex.RLock()
+	inBackoff := time.Now().Before(gt.backoffUntil)
+	gt.backoffMutex.RUnlock()
+	if inBackoff {
+		return nil
+	}
+
+	// Respect the external API's rate limit; drop the lookup rather than queue
+	if gt.lookupLimiter != nil && !gt.lookupLimiter.Allow() {
+		gt.logger.Debug("Geo lookup skipped by rate limiter", zap.String("ip", ip))
+		return nil
+	}
+
 	// Fetch from API
 	geoData := gt.fetchGeographicData(ip)
+	gt.cacheMutex.Lock()
 	if geoData != nil {
 		// Cache the result
-		gt.cacheMutex.Lock()
 		gt.cache[ip] = geoData
-		gt.cacheMutex.Unlock()
+		delete(gt.negativeCache, ip)
+	} else {
+		// Negative-cache the failure so the next requests back off briefly
+		gt.negativeCache[ip] = time.Now().Add(gt.negativeTTL)
 	}
+	gt.cacheMutex.Unlock()
 
 	return geoData
 }
 
-// fetchGeographicData fetches geographic data from ip-api.com
+// fetchGeographicData fetches geographic data from ip-api.com, retrying
+// transient failures (timeouts, 5xx) with a short backoff under an overall
+// deadline. Definitive answers (4xx, malformed bodies) are not retried.
 func (gt *GeographicTracker) fetchGeographicData(ip string) *GeographicData {
-	url := fmt.Sprintf("http://ip-api.com/json/%s?fields=status,message,country,countryCode,region,regionName,city,lat,lon,timezone,isp", ip)
+	deadline := time.Now().Add(overallLookupDeadline)
+	for attempt := 1; attempt <= maxLookupAttempts; attempt++ {
+		geoData, retryable := gt.fetchGeographicDataOnce(ip)
+		if geoData != nil || !retryable {
+			return geoData
+		}
+		backoff := lookupRetryBackoff * time.Duration(attempt)
+		if attempt == maxLookupAttempts || time.Now().Add(backoff).After(deadline) {
+			break
+		}
+		gt.logger.Debug("Retrying geo lookup after transient failure",
+			zap.String("ip", ip),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff))
+		time.Sleep(backoff)
+	}
+	return nil
+}
+
+// fetchGeographicDataOnce performs a single lookup attempt. The second return
+// says whether a failure is worth retrying.
+func (gt *GeographicTracker) fetchGeographicDataOnce(ip string) (*GeographicData, bool) {
+	url := fmt.Sprintf("%s/%s?fields=status,message,country,countryCode,region,regionName,city,lat,lon,timezone,isp", gt.apiBaseURL, ip)
 
 	resp, err := gt.httpClient.Get(url)
 	if err != nil {
+		// Network errors and timeouts are transient by nature
 		gt.logger.Error("Failed to fetch geographic data", zap.String("ip", ip), zap.Error(err))
-		return nil
+		return nil, true
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		gt.backoffMutex.Lock()
+		gt.backoffUntil = time.Now().Add(lookupBackoffDuration)
+		gt.backoffMutex.Unlock()
+		gt.logger.Warn("Geo API rate limited us, backing off",
+			zap.String("ip", ip),
+			zap.Duration("backoff", lookupBackoffDuration))
+		return nil, false
+	}
+
+	if resp.StatusCode >= 500 {
+		gt.logger.Warn("Geo API returned a server error",
+			zap.String("ip", ip),
+			zap.Int("status", resp.StatusCode))
+		return nil, true
+	}
+	if resp.StatusCode >= 400 {
+		gt.logger.Warn("Geo API rejected the lookup",
+			zap.String("ip", ip),
+			zap.Int("status", resp.StatusCode))
+		return nil, false
+	}
+
 	var result struct {
 		Status      string  `json:"status"`
 		Message     string  `json:"message"`
@@ -195,12 +338,12 @@ func (gt *GeographicTracker) fetchGeographicData(ip string) *GeographicData {
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		gt.logger.Error("Failed to decode geographic data", zap.String("ip", ip), zap.Error(err))
-		return nil
+		return nil, false
 	}
 
 	if result.Status != "success" {
 		gt.logger.Warn("Geographic lookup failed", zap.String("ip", ip), zap.String("message", result.Message))
-		return nil
+		return nil, false
 	}
 
 	geoData := &GeographicData{
@@ -219,11 +362,17 @@ func (gt *GeographicTracker) fetchGeographicData(ip string) *GeographicData {
 		zap.String("country", geoData.Country),
 		zap.String("city", geoData.City))
 
-	return geoData
+	return geoData, false
 }
 
 // TrackRequest tracks a request from a specific IP address
 func (gt *GeographicTracker) TrackRequest(ip string) {
+	gt.TrackRequestN(ip, 1)
+}
+
+// TrackRequestN tracks n requests from a specific IP address. Used by request
+// sampling, where each sampled request stands in for n real ones.
+func (gt *GeographicTracker) TrackRequestN(ip string, n uint64) {
 	geoData := gt.GetGeographicData(ip)
 	if geoData == nil {
 		return
@@ -233,13 +382,13 @@ func (gt *GeographicTracker) TrackRequest(ip string) {
 	defer gt.statsMutex.Unlock()
 
 	if stats, exists := gt.stats[geoData.Country]; exists {
-		stats.Requests++
+		stats.Requests += n
 		stats.LastSeen = time.Now().Unix()
 	} else {
 		gt.stats[geoData.Country] = &CountryStats{
 			Country:     geoData.Country,
 			CountryCode: geoData.CountryCode,
-			Requests:    1,
+			Requests:    n,
 			LastSeen:    time.Now().Unix(),
 			Percentage:  0, // Will be calculated when getting stats
 		}
@@ -287,6 +436,14 @@ func (gt *GeographicTracker) GetGeographicStats() []*CountryStats {
 	return result
 }
 
+// ResetStats clears all accumulated country statistics. Safe to call while
+// requests are still being tracked.
+func (gt *GeographicTracker) ResetStats() {
+	gt.statsMutex.Lock()
+	defer gt.statsMutex.Unlock()
+	gt.stats = make(map[string]*CountryStats)
+}
+
 // GetCacheSize returns the current cache size
 func (gt *GeographicTracker) GetCacheSize() int {
 	gt.cacheMutex.RLock()
@@ -0,0 +1,186 @@
+package geographic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+func TestResetStats(t *testing.T) {
+	tracker := NewGeographicTracker(zap.NewNop())
+
+	// Private IPs resolve to the "Local Network" stub without network calls
+	tracker.TrackRequest("10.0.0.1")
+	tracker.TrackRequest("192.168.1.5")
+
+	if len(tracker.GetGeographicStats()) == 0 {
+		t.Fatal("expected stats to be recorded before reset")
+	}
+
+	tracker.ResetStats()
+
+	if stats := tracker.GetGeographicStats(); len(stats) != 0 {
+		t.Errorf("expected empty stats after reset, got %d entries", len(stats))
+	}
+}
+
+func TestResetStatsConcurrentWithTracking(t *testing.T) {
+	tracker := NewGeographicTracker(zap.NewNop())
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			tracker.TrackRequest("10.0.0.1")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			tracker.ResetStats()
+		}
+	}()
+	wg.Wait()
+}
+
+func newStubGeoAPI(t *testing.T, calls *int64, delay time.Duration) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(calls, 1)
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","country":"Germany","countryCode":"DE","regionName":"Berlin","city":"Berlin","lat":52.5,"lon":13.4,"timezone":"Europe/Berlin","isp":"Test ISP"}`))
+	}))
+}
+
+func TestConcurrentLookupsCoalesceToOneCall(t *testing.T) {
+	var calls int64
+	stub := newStubGeoAPI(t, &calls, 50*time.Millisecond)
+	defer stub.Close()
+
+	tracker := NewGeographicTracker(zap.NewNop())
+	tracker.SetAPIBaseURL(stub.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := tracker.GetGeographicData("8.8.8.8")
+			if data == nil || data.Country != "Germany" {
+				t.Errorf("expected coalesced lookup to return data, got %v", data)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Errorf("expected exactly 1 outbound call for concurrent lookups, got %d", got)
+	}
+}
+
+func TestLookupRateLimiterCapsOutboundCalls(t *testing.T) {
+	var calls int64
+	stub := newStubGeoAPI(t, &calls, 0)
+	defer stub.Close()
+
+	tracker := NewGeographicTracker(zap.NewNop())
+	tracker.SetAPIBaseURL(stub.URL)
+	// One lookup allowed, then none for an hour
+	tracker.SetLookupLimiter(rate.NewLimiter(rate.Every(time.Hour), 1))
+
+	if data := tracker.GetGeographicData("8.8.8.8"); data == nil {
+		t.Fatal("expected first lookup to succeed")
+	}
+	if data := tracker.GetGeographicData("9.9.9.9"); data != nil {
+		t.Errorf("expected rate-limited lookup to be dropped, got %v", data)
+	}
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Errorf("expected 1 outbound call, got %d", got)
+	}
+}
+
+func TestNegativeCachingOfFailedLookups(t *testing.T) {
+	var calls int64
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"fail","message":"reserved range"}`))
+	}))
+	defer stub.Close()
+
+	tracker := NewGeographicTracker(zap.NewNop())
+	tracker.SetAPIBaseURL(stub.URL)
+	tracker.SetNegativeCacheTTL(50 * time.Millisecond)
+
+	// First lookup fails and is negative-cached
+	if data := tracker.GetGeographicData("8.8.8.8"); data != nil {
+		t.Fatalf("expected failed lookup to return nil, got %v", data)
+	}
+	// Within the TTL the failure is served from cache without an outbound call
+	if data := tracker.GetGeographicData("8.8.8.8"); data != nil {
+		t.Errorf("expected negative-cached lookup to return nil, got %v", data)
+	}
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Errorf("expected 1 outbound call within TTL, got %d", got)
+	}
+
+	// After the TTL the lookup is retried
+	time.Sleep(60 * time.Millisecond)
+	tracker.GetGeographicData("8.8.8.8")
+	if got := atomic.LoadInt64(&calls); got != 2 {
+		t.Errorf("expected retry after TTL, got %d calls", got)
+	}
+}
+
+func TestFlakyGeoAPISucceedsOnRetry(t *testing.T) {
+	var calls int64
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","country":"Germany","countryCode":"DE","regionName":"Berlin","city":"Berlin","lat":52.5,"lon":13.4,"timezone":"Europe/Berlin","isp":"Test ISP"}`))
+	}))
+	defer stub.Close()
+
+	tracker := NewGeographicTracker(zap.NewNop())
+	tracker.SetAPIBaseURL(stub.URL)
+
+	data := tracker.GetGeographicData("8.8.8.8")
+	if data == nil || data.Country != "Germany" {
+		t.Fatalf("expected retry to recover from the transient 502, got %v", data)
+	}
+	if got := atomic.LoadInt64(&calls); got != 2 {
+		t.Errorf("expected 2 outbound calls (failure then success), got %d", got)
+	}
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	var calls int64
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer stub.Close()
+
+	tracker := NewGeographicTracker(zap.NewNop())
+	tracker.SetAPIBaseURL(stub.URL)
+
+	if data := tracker.GetGeographicData("8.8.8.8"); data != nil {
+		t.Fatalf("expected 403 lookup to fail, got %v", data)
+	}
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Errorf("expected a 4xx to not be retried, got %d calls", got)
+	}
+}
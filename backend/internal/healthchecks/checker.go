@@ -1,9 +1,16 @@
 package healthchecks
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/config"
@@ -17,26 +24,105 @@ type Checker interface {
 	Check(server *model.BackendServer) error
 }
 
-// HTTPHealthChecker performs HTTP GET health checks.
+// dnsPreflightTimeout bounds the DNS lookup that runs ahead of each check, so
+// an unresolvable backend hostname fails the check immediately instead of
+// stalling the check loop for the full connect timeout.
+const dnsPreflightTimeout = 1 * time.Second
+
+// resolveHostFast verifies the host resolves within dnsPreflightTimeout.
+// IP literals skip the lookup entirely.
+func resolveHostFast(host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dnsPreflightTimeout)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		return fmt.Errorf("health check DNS resolution failed for %q: %w", host, err)
+	}
+	return nil
+}
+
+// maxHealthCheckBodyBytes caps how much of a health response body is read for
+// matching, so a huge or endlessly streaming body can't stall the checker.
+const maxHealthCheckBodyBytes = 64 * 1024
+
+// HTTPHealthChecker performs HTTP GET health checks. When expectBody is set
+// the response body must contain it; the body read is bounded both by the
+// client timeout and by maxHealthCheckBodyBytes.
 type HTTPHealthChecker struct {
-	client *http.Client
-	path   string
+	client      *http.Client
+	path        string
+	expectBody  string // Optional substring required in the response body
+	bearerToken string // Optional bearer token for protected health endpoints
+	basicUser   string // Optional basic auth credentials; token wins when both set
+	basicPass   string
 }
 
 func NewHTTPHealthChecker(timeout time.Duration, path string) *HTTPHealthChecker {
 	return &HTTPHealthChecker{
 		client: &http.Client{
 			Timeout: timeout,
+			// Don't follow redirects by default: a backend 302ing to some
+			// external page should surface as a failed check, not pass
+			// because the redirect target happened to return 200.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
 		},
 		path: path,
 	}
 }
 
+// SetRedirectPolicy controls how the checker treats redirect responses.
+// "follow" chases redirects up to maxHops (Go's default of 10 when zero);
+// anything else keeps the default of returning the redirect itself, which
+// fails the status check.
+func (hc *HTTPHealthChecker) SetRedirectPolicy(policy string, maxHops int) {
+	if policy != "follow" {
+		return
+	}
+	if maxHops <= 0 {
+		hc.client.CheckRedirect = nil // Go's default: follow up to 10 hops
+		return
+	}
+	hc.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxHops {
+			return fmt.Errorf("stopped after %d redirects", maxHops)
+		}
+		return nil
+	}
+}
+
+// SetAuth configures credentials for protected health endpoints. A non-empty
+// bearer token takes precedence over basic auth.
+func (hc *HTTPHealthChecker) SetAuth(auth config.HealthCheckAuthConfig) {
+	hc.bearerToken = auth.BearerToken
+	hc.basicUser = auth.Username
+	hc.basicPass = auth.Password
+}
+
+// NewHTTPBodyHealthChecker creates an HTTP checker that additionally requires
+// the given substring in the response body.
+func NewHTTPBodyHealthChecker(timeout time.Duration, path, expectBody string) *HTTPHealthChecker {
+	hc := NewHTTPHealthChecker(timeout, path)
+	hc.expectBody = expectBody
+	return hc
+}
+
 func (hc *HTTPHealthChecker) Check(server *model.BackendServer) error {
-	req, err := http.NewRequest("GET", server.URL.String()+hc.path, nil)
+	if err := resolveHostFast(server.HealthURL().Hostname()); err != nil {
+		return err
+	}
+	req, err := http.NewRequest("GET", server.HealthURL().String()+hc.path, nil)
 	if err != nil {
 		return err
 	}
+	if hc.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+hc.bearerToken)
+	} else if hc.basicUser != "" {
+		req.SetBasicAuth(hc.basicUser, hc.basicPass)
+	}
 	
 	// Measure response time
 	start := time.Now()
@@ -53,22 +139,48 @@ func (hc *HTTPHealthChecker) Check(server *model.BackendServer) error {
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("http status not OK: %d", resp.StatusCode)
 	}
+	if hc.expectBody != "" {
+		// The client timeout covers the body read, and the limit stops an
+		// endless stream once enough bytes arrived to decide the match.
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthCheckBodyBytes))
+		server.SetResponseTime(time.Since(start))
+		if strings.Contains(string(body), hc.expectBody) {
+			return nil // A partial read that already contains the match is enough
+		}
+		if err != nil {
+			return fmt.Errorf("health check body read failed: %w", err)
+		}
+		return fmt.Errorf("health check body mismatch: expected %q in response", hc.expectBody)
+	}
 	return nil
 }
 
-// TCPHealthChecker performs TCP dial health checks.
+// TCPHealthChecker performs TCP dial health checks. When send/expect are set
+// it additionally writes the send string and requires the expect substring in
+// the response, validating a simple protocol banner beyond mere connectivity.
 type TCPHealthChecker struct {
 	timeout time.Duration
+	send    string // Optional payload written after connecting
+	expect  string // Optional substring required in the response
 }
 
 func NewTCPHealthChecker(timeout time.Duration) *TCPHealthChecker {
 	return &TCPHealthChecker{timeout: timeout}
 }
 
+// NewTCPBannerHealthChecker creates a TCP checker that sends a payload and
+// verifies the response contains the expected substring.
+func NewTCPBannerHealthChecker(timeout time.Duration, send, expect string) *TCPHealthChecker {
+	return &TCPHealthChecker{timeout: timeout, send: send, expect: expect}
+}
+
 func (tc *TCPHealthChecker) Check(server *model.BackendServer) error {
+	if err := resolveHostFast(server.HealthURL().Hostname()); err != nil {
+		return err
+	}
 	// Measure response time
 	start := time.Now()
-	conn, err := net.DialTimeout("tcp", server.URL.Host, tc.timeout)
+	conn, err := net.DialTimeout("tcp", server.HealthURL().Host, tc.timeout)
 	responseTime := time.Since(start)
 	
 	// Always record response time, even for failed connections
@@ -77,41 +189,305 @@ func (tc *TCPHealthChecker) Check(server *model.BackendServer) error {
 	if err != nil {
 		return err
 	}
-	_ = conn.Close()
+	defer conn.Close()
+
+	if tc.send == "" && tc.expect == "" {
+		return nil // Connect-only check, the default
+	}
+
+	deadline := time.Now().Add(tc.timeout)
+	_ = conn.SetDeadline(deadline)
+	if tc.send != "" {
+		if _, err := conn.Write([]byte(tc.send)); err != nil {
+			return fmt.Errorf("tcp banner write failed: %w", err)
+		}
+	}
+	if tc.expect != "" {
+		buf := make([]byte, 512)
+		n, err := conn.Read(buf)
+		server.SetResponseTime(time.Since(start))
+		if err != nil {
+			return fmt.Errorf("tcp banner read failed: %w", err)
+		}
+		if !strings.Contains(string(buf[:n]), tc.expect) {
+			return fmt.Errorf("tcp banner mismatch: expected %q in response", tc.expect)
+		}
+	}
 	return nil
 }
 
+// TLSHealthChecker verifies a backend by completing a TLS handshake, for
+// TLS-only backends where a full HTTP probe is overkill. Certificate
+// verification can be skipped for self-signed deployments. The recorded
+// response time is the handshake latency.
+type TLSHealthChecker struct {
+	timeout    time.Duration
+	skipVerify bool
+}
+
+func NewTLSHealthChecker(timeout time.Duration, skipVerify bool) *TLSHealthChecker {
+	return &TLSHealthChecker{timeout: timeout, skipVerify: skipVerify}
+}
+
+func (tc *TLSHealthChecker) Check(server *model.BackendServer) error {
+	target := server.HealthURL()
+	if err := resolveHostFast(target.Hostname()); err != nil {
+		return err
+	}
+	dialer := &net.Dialer{Timeout: tc.timeout}
+	start := time.Now()
+	conn, err := tls.DialWithDialer(dialer, "tcp", target.Host, &tls.Config{
+		ServerName:         target.Hostname(),
+		InsecureSkipVerify: tc.skipVerify,
+	})
+	server.SetResponseTime(time.Since(start)) // Handshake latency, even on failure
+	if err != nil {
+		return fmt.Errorf("tls handshake failed: %w", err)
+	}
+	return conn.Close()
+}
+
+// CompositeChecker runs several checkers against the same backend. With
+// requireAll set every sub-check must pass (AND semantics); otherwise a
+// single passing sub-check is enough (OR semantics). The backend's response
+// time is set to the slowest sub-check, the figure that actually gates how
+// long a probe round takes.
+type CompositeChecker struct {
+	checkers   []Checker
+	requireAll bool
+}
+
+// NewCompositeChecker combines checkers with AND (requireAll) or OR semantics.
+func NewCompositeChecker(requireAll bool, checkers ...Checker) *CompositeChecker {
+	return &CompositeChecker{checkers: checkers, requireAll: requireAll}
+}
+
+func (cc *CompositeChecker) Check(server *model.BackendServer) error {
+	var errs []error
+	var slowest time.Duration
+	passed := 0
+	for _, checker := range cc.checkers {
+		start := time.Now()
+		err := checker.Check(server)
+		if elapsed := time.Since(start); elapsed > slowest {
+			slowest = elapsed
+		}
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			passed++
+		}
+	}
+	server.SetResponseTime(slowest)
+	if cc.requireAll && len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if !cc.requireAll && passed == 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// maxConcurrentChecks bounds how many backend probes run at once, so a burst
+// of slow backends can't pile up goroutines while still letting checks for
+// other backends proceed alongside a slow one.
+const maxConcurrentChecks = 8
+
+// checkOffsets spreads n health checks across the interval: each backend gets
+// its own slot plus random jitter within that slot, so probes never fire in a
+// single burst against the backends.
+func checkOffsets(rng *rand.Rand, n int, interval time.Duration) []time.Duration {
+	offsets := make([]time.Duration, n)
+	if n == 0 {
+		return offsets
+	}
+	slot := interval / time.Duration(n)
+	for i := range offsets {
+		jitter := time.Duration(0)
+		if slot > 0 {
+			jitter = time.Duration(rng.Int63n(int64(slot)))
+		}
+		offsets[i] = slot*time.Duration(i) + jitter
+	}
+	return offsets
+}
+
+// healthCheckPath resolves the probe path for a backend: a path already on
+// the backend's health URL wins, then the scheme-specific default, then the
+// generic Path. TCP backends never use a path.
+func healthCheckPath(server *model.BackendServer, cfg config.HealthCheckConfig) string {
+	if p := server.HealthURL().Path; p != "" && p != "/" {
+		return "" // The health URL carries its own path; don't append another
+	}
+	if server.URL.Scheme == "https" && cfg.HTTPSPath != "" {
+		return cfg.HTTPSPath
+	}
+	return cfg.Path
+}
+
+// CheckerFactory builds a Checker for one backend from the health check
+// config, so new checker types can plug in per URL scheme.
+type CheckerFactory func(server *model.BackendServer, cfg config.HealthCheckConfig) Checker
+
+var (
+	checkerRegistryMu sync.RWMutex
+	checkerRegistry   = map[string]CheckerFactory{}
+)
+
+// RegisterChecker maps a URL scheme to a checker factory. Registering an
+// already-mapped scheme replaces the previous factory, which lets deployments
+// override the built-ins.
+func RegisterChecker(scheme string, factory CheckerFactory) {
+	checkerRegistryMu.Lock()
+	defer checkerRegistryMu.Unlock()
+	checkerRegistry[scheme] = factory
+}
+
+// newHTTPChecker is the built-in factory for http/https backends, applying
+// the configured path, body match, redirect policy, and composite mode.
+func newHTTPChecker(server *model.BackendServer, cfg config.HealthCheckConfig) Checker {
+	path := healthCheckPath(server, cfg)
+	var hc *HTTPHealthChecker
+	if cfg.ExpectBody != "" {
+		hc = NewHTTPBodyHealthChecker(time.Duration(cfg.TimeoutSeconds)*time.Second, path, cfg.ExpectBody)
+	} else {
+		hc = NewHTTPHealthChecker(time.Duration(cfg.TimeoutSeconds)*time.Second, path)
+	}
+	hc.SetRedirectPolicy(cfg.RedirectPolicy, cfg.MaxRedirects)
+	hc.SetAuth(cfg.Auth)
+	if cfg.Composite != "" {
+		// Combined check: a TCP connect alongside the HTTP probe, with
+		// "all" demanding both and "any" accepting either.
+		tcp := NewTCPHealthChecker(time.Duration(cfg.TimeoutSeconds) * time.Second)
+		return NewCompositeChecker(cfg.Composite != "any", hc, tcp)
+	}
+	return hc
+}
+
+// newTLSChecker is the built-in factory for tls:// backends: a bare TLS
+// handshake, honoring the configured certificate verification policy.
+func newTLSChecker(server *model.BackendServer, cfg config.HealthCheckConfig) Checker {
+	return NewTLSHealthChecker(time.Duration(cfg.TimeoutSeconds)*time.Second, cfg.TLSSkipVerify)
+}
+
+// newTCPChecker is the built-in factory for raw TCP backends, upgrading to a
+// banner check when a send/expect payload is configured.
+func newTCPChecker(server *model.BackendServer, cfg config.HealthCheckConfig) Checker {
+	if cfg.TCPSend != "" || cfg.TCPExpect != "" {
+		return NewTCPBannerHealthChecker(time.Duration(cfg.TimeoutSeconds)*time.Second, cfg.TCPSend, cfg.TCPExpect)
+	}
+	return NewTCPHealthChecker(time.Duration(cfg.TimeoutSeconds) * time.Second)
+}
+
+func init() {
+	RegisterChecker("http", newHTTPChecker)
+	RegisterChecker("https", newHTTPChecker)
+	RegisterChecker("tcp", newTCPChecker)
+	RegisterChecker("tls", newTLSChecker)
+}
+
+// checkerFor resolves the checker for a backend via the registry, with raw
+// TCP as the fallback for unregistered schemes. A configured forceChecker
+// overrides the scheme entirely, e.g. TCP-only probing of HTTP backends that
+// expose no health path.
+func checkerFor(server *model.BackendServer, cfg config.HealthCheckConfig) Checker {
+	key := server.URL.Scheme
+	if cfg.ForceChecker != "" {
+		key = cfg.ForceChecker
+	}
+	checkerRegistryMu.RLock()
+	factory, ok := checkerRegistry[key]
+	checkerRegistryMu.RUnlock()
+	if !ok {
+		factory = newTCPChecker
+	}
+	return factory(server, cfg)
+}
+
+// checkServer runs a single health check against a backend and updates its
+// status in the pool.
+func checkServer(pool *loadbalancing.ServerPool, server *model.BackendServer, cfg config.HealthCheckConfig, log *zap.Logger) {
+	checker := checkerFor(server, cfg)
+
+	healthyAfter := cfg.HealthyThreshold
+	if healthyAfter <= 0 {
+		healthyAfter = 1
+	}
+	unhealthyAfter := cfg.UnhealthyThreshold
+	if unhealthyAfter <= 0 {
+		unhealthyAfter = 1
+	}
+
+	checkStart := time.Now()
+	err := checker.Check(server)
+	server.RecordCheckDuration(time.Since(checkStart), err != nil)
+	if err != nil {
+		failures := server.RecordCheckFailure()
+		if failures >= unhealthyAfter && server.HealthStatus != model.UNHEALTHY {
+			pool.SetBackendStatusWithReason(server.ID, model.UNHEALTHY, err.Error())
+			log.Error("Backend server unhealthy", zap.Error(err), zap.String("server_id", server.ID), zap.String("url", server.URL.String()), zap.Int("consecutiveFailures", failures))
+		} else if server.HealthStatus != model.UNHEALTHY {
+			log.Warn("Backend server health check failed", zap.Error(err), zap.String("server_id", server.ID), zap.Int("consecutiveFailures", failures), zap.Int("unhealthyThreshold", unhealthyAfter))
+		}
+	} else {
+		successes := server.RecordCheckSuccess()
+		if successes >= healthyAfter && server.HealthStatus != model.HEALTHY {
+			// Honor the cool-down: a backend that just went unhealthy must
+			// stay out of rotation for the configured period even if checks
+			// start passing again, so a lucky blip can't cause flapping.
+			if cfg.CooldownSeconds > 0 && server.HealthStatus == model.UNHEALTHY {
+				if since := server.UnhealthySince(); !since.IsZero() && time.Since(since) < time.Duration(cfg.CooldownSeconds)*time.Second {
+					log.Info("Backend server passing checks but still in cool-down",
+						zap.String("server_id", server.ID),
+						zap.Duration("remaining", time.Duration(cfg.CooldownSeconds)*time.Second-time.Since(since)))
+					return
+				}
+			}
+			pool.SetBackendStatusWithReason(server.ID, model.HEALTHY, "health checks passing")
+			log.Info("Backend server healthy again", zap.String("server_id", server.ID), zap.String("url", server.URL.String()))
+		}
+	}
+}
+
+// runHealthCheckRound schedules one jittered, concurrent round of checks for
+// every backend in the pool. Checks run in their own goroutines, bounded by
+// the sem worker slots, so one slow or hung backend never delays the others.
+func runHealthCheckRound(pool *loadbalancing.ServerPool, cfg config.HealthCheckConfig, log *zap.Logger, rng *rand.Rand, sem chan struct{}, interval time.Duration) {
+	servers := pool.GetServers() // Get all servers, even if marked unhealthy
+	offsets := checkOffsets(rng, len(servers), interval)
+	for i, server := range servers {
+		if server.HealthStatus == model.MAINTENANCE {
+			continue // Skip health checks for servers in maintenance
+		}
+		srv := server
+		time.AfterFunc(offsets[i], func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			checkServer(pool, srv, cfg, log)
+		})
+	}
+}
+
 // StartHealthChecks begins a goroutine to periodically check backend health.
+// Each backend's check is jittered to a distinct offset within the interval
+// to avoid probing every backend at the same instant, and checks run
+// concurrently under a bounded worker pool.
 func StartHealthChecks(pool *loadbalancing.ServerPool, cfg config.HealthCheckConfig, log *zap.Logger) {
-	ticker := time.NewTicker(time.Duration(cfg.IntervalSeconds) * time.Second)
+	interval := time.Duration(cfg.IntervalSeconds) * time.Second
+	ticker := time.NewTicker(interval)
 	go func() {
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		sem := make(chan struct{}, maxConcurrentChecks)
+		// Kick off one un-jittered pass right away so backends don't sit in
+		// their initial UNHEALTHY state until the first tick, which would make
+		// readiness flap at boot. Opt out via skipInitialCheck.
+		if !cfg.SkipInitialCheck {
+			log.Debug("Running initial health check pass...")
+			runHealthCheckRound(pool, cfg, log, rng, sem, 0)
+		}
 		for range ticker.C {
 			log.Debug("Running health checks...")
-			servers := pool.GetServers() // Get all servers, even if marked unhealthy
-			for _, server := range servers {
-				if server.HealthStatus == model.MAINTENANCE {
-					continue // Skip health checks for servers in maintenance
-				}
-
-				var checker Checker
-				// You can make this configurable per server or global in config
-				if server.URL.Scheme == "http" || server.URL.Scheme == "https" {
-					checker = NewHTTPHealthChecker(time.Duration(cfg.TimeoutSeconds)*time.Second, cfg.Path)
-				} else {
-					checker = NewTCPHealthChecker(time.Duration(cfg.TimeoutSeconds) * time.Second)
-				}
-
-				err := checker.Check(server)
-				if err != nil {
-					server.SetStatus(model.UNHEALTHY)
-					log.Error("Backend server unhealthy", zap.Error(err), zap.String("server_id", server.ID), zap.String("url", server.URL.String()))
-				} else {
-					if server.HealthStatus != model.HEALTHY {
-						server.SetStatus(model.HEALTHY)
-						log.Info("Backend server healthy again", zap.String("server_id", server.ID), zap.String("url", server.URL.String()))
-					}
-				}
-			}
+			runHealthCheckRound(pool, cfg, log, rng, sem, interval)
 		}
 	}()
 }
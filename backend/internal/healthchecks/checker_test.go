@@ -1,6 +1,8 @@
 package healthchecks
 
 import (
+	"errors"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +10,10 @@ import (
 	"testing"
 	"time"
 
+	"go.uber.org/zap"
+
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/config"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
 )
 
@@ -81,3 +87,751 @@ func mustParseURL(raw string) *url.URL {
 	}
 	return u
 }
+
+func TestCheckOffsetsSpreadWithinInterval(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	interval := 10 * time.Second
+	const n = 5
+
+	offsets := checkOffsets(rng, n, interval)
+	if len(offsets) != n {
+		t.Fatalf("expected %d offsets, got %d", n, len(offsets))
+	}
+
+	slot := interval / n
+	seen := make(map[time.Duration]bool)
+	for i, offset := range offsets {
+		if offset < 0 || offset >= interval {
+			t.Errorf("offset %d (%v) outside the interval", i, offset)
+		}
+		// Each backend stays within its own slot, so no two bursts coincide
+		if offset < slot*time.Duration(i) || offset >= slot*time.Duration(i+1) {
+			t.Errorf("offset %d (%v) outside its slot [%v, %v)", i, offset, slot*time.Duration(i), slot*time.Duration(i+1))
+		}
+		if seen[offset] {
+			t.Errorf("offset %d (%v) duplicates an earlier offset", i, offset)
+		}
+		seen[offset] = true
+	}
+}
+
+func TestSlowBackendDoesNotDelayOtherChecks(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer fast.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	for i, backendURL := range []string{slow.URL, fast.URL} {
+		server, err := model.NewBackendServer("s"+string(rune('0'+i)), backendURL, 1)
+		if err != nil {
+			t.Fatalf("failed to create backend server: %v", err)
+		}
+		pool.AddServer(server)
+	}
+
+	cfg := config.HealthCheckConfig{IntervalSeconds: 1, TimeoutSeconds: 2}
+	rng := rand.New(rand.NewSource(1))
+	sem := make(chan struct{}, maxConcurrentChecks)
+
+	// A short scheduling interval keeps jitter offsets small; the slow
+	// backend's 500ms handler dominates its check duration.
+	start := time.Now()
+	runHealthCheckRound(pool, cfg, zap.NewNop(), rng, sem, 50*time.Millisecond)
+
+	// The fast backend must be marked healthy well before the slow check ends
+	deadline := time.Now().Add(400 * time.Millisecond)
+	fastHealthy := false
+	for time.Now().Before(deadline) {
+		for _, srv := range pool.GetServers() {
+			if srv.ID == "s1" && srv.IsAlive() {
+				fastHealthy = true
+			}
+		}
+		if fastHealthy {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !fastHealthy {
+		t.Fatalf("fast backend not checked promptly; waited %v behind the slow backend", time.Since(start))
+	}
+}
+
+func TestHTTPHealthCheckerUsesSeparateHealthPort(t *testing.T) {
+	// Service port always fails; the dedicated health port succeeds
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer service.Close()
+	health := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer health.Close()
+
+	server, err := model.NewBackendServer("split", service.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	if err := server.SetHealthCheckEndpoint(health.URL, 0); err != nil {
+		t.Fatalf("failed to set health check endpoint: %v", err)
+	}
+
+	checker := NewHTTPHealthChecker(time.Second, "")
+	if err := checker.Check(server); err != nil {
+		t.Errorf("expected health check against the health port to pass: %v", err)
+	}
+
+	// Without the override the failing service port is probed
+	server.HealthCheckURL = nil
+	if err := checker.Check(server); err == nil {
+		t.Error("expected health check against the service port to fail")
+	}
+}
+
+func TestSetHealthCheckEndpointPortOnly(t *testing.T) {
+	server, err := model.NewBackendServer("porty", "http://localhost:8080", 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	if err := server.SetHealthCheckEndpoint("", 8081); err != nil {
+		t.Fatalf("failed to set health check port: %v", err)
+	}
+	if got := server.HealthURL().Host; got != "localhost:8081" {
+		t.Errorf("expected health host localhost:8081, got %s", got)
+	}
+	if server.URL.Host != "localhost:8080" {
+		t.Errorf("expected service host untouched, got %s", server.URL.Host)
+	}
+}
+
+func TestUnhealthyThresholdRequiresConsecutiveFailures(t *testing.T) {
+	backendUp := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !backendUp {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("threshold", ts.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	cfg := config.HealthCheckConfig{TimeoutSeconds: 2, UnhealthyThreshold: 3}
+	log := zap.NewNop()
+
+	backendUp = false
+	for i := 1; i <= 2; i++ {
+		checkServer(pool, server, cfg, log)
+		if !server.IsAlive() {
+			t.Fatalf("server marked unhealthy after %d failures, threshold is 3", i)
+		}
+	}
+	checkServer(pool, server, cfg, log)
+	if server.IsAlive() {
+		t.Error("server still alive after 3 consecutive failures with threshold 3")
+	}
+}
+
+func TestHealthyThresholdRequiresConsecutiveSuccesses(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("recovery", ts.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	pool.AddServer(server) // Starts UNHEALTHY
+
+	cfg := config.HealthCheckConfig{TimeoutSeconds: 2, HealthyThreshold: 2}
+	log := zap.NewNop()
+
+	checkServer(pool, server, cfg, log)
+	if server.IsAlive() {
+		t.Fatal("server marked healthy after 1 success, threshold is 2")
+	}
+	checkServer(pool, server, cfg, log)
+	if !server.IsAlive() {
+		t.Error("server still unhealthy after 2 consecutive successes with threshold 2")
+	}
+}
+
+func TestFailureStreakResetsOnSuccess(t *testing.T) {
+	backendUp := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !backendUp {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("streak", ts.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	cfg := config.HealthCheckConfig{TimeoutSeconds: 2, UnhealthyThreshold: 2}
+	log := zap.NewNop()
+
+	// fail, succeed, fail: no two consecutive failures, so the server stays up
+	backendUp = false
+	checkServer(pool, server, cfg, log)
+	backendUp = true
+	checkServer(pool, server, cfg, log)
+	backendUp = false
+	checkServer(pool, server, cfg, log)
+	if !server.IsAlive() {
+		t.Error("server marked unhealthy without consecutive failures reaching the threshold")
+	}
+}
+
+// startBannerServer listens on a loopback port and answers every connection's
+// first read with the given response.
+func startBannerServer(t *testing.T, response string) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				buf := make([]byte, 64)
+				_, _ = c.Read(buf)
+				_, _ = c.Write([]byte(response))
+			}(conn)
+		}
+	}()
+	return ln
+}
+
+func TestTCPBannerHealthCheckerMatchingResponse(t *testing.T) {
+	ln := startBannerServer(t, "PONG\r\n")
+	defer ln.Close()
+
+	server, err := model.NewBackendServer("banner-ok", "tcp://"+ln.Addr().String(), 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+
+	checker := NewTCPBannerHealthChecker(2*time.Second, "PING\r\n", "PONG")
+	if err := checker.Check(server); err != nil {
+		t.Errorf("expected banner check to pass, got: %v", err)
+	}
+}
+
+func TestTCPBannerHealthCheckerWrongResponse(t *testing.T) {
+	ln := startBannerServer(t, "ERR unknown command\r\n")
+	defer ln.Close()
+
+	server, err := model.NewBackendServer("banner-bad", "tcp://"+ln.Addr().String(), 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+
+	checker := NewTCPBannerHealthChecker(2*time.Second, "PING\r\n", "PONG")
+	if err := checker.Check(server); err == nil {
+		t.Error("expected banner check to fail for a non-matching response")
+	}
+}
+
+func TestTCPHealthCheckerConnectOnlyStillPasses(t *testing.T) {
+	ln := startBannerServer(t, "")
+	defer ln.Close()
+
+	server, err := model.NewBackendServer("connect-only", "tcp://"+ln.Addr().String(), 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+
+	checker := NewTCPHealthChecker(2 * time.Second)
+	if err := checker.Check(server); err != nil {
+		t.Errorf("expected connect-only check to pass, got: %v", err)
+	}
+}
+
+func TestHTTPBodyHealthCheckerMatchesBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer ts.Close()
+
+	server, err := model.NewBackendServer("body-ok", ts.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+
+	checker := NewHTTPBodyHealthChecker(2*time.Second, "", `"status":"ok"`)
+	if err := checker.Check(server); err != nil {
+		t.Errorf("expected body check to pass, got: %v", err)
+	}
+
+	mismatch := NewHTTPBodyHealthChecker(2*time.Second, "", "absent")
+	if err := mismatch.Check(server); err == nil {
+		t.Error("expected body check to fail for a non-matching body")
+	}
+}
+
+func TestHTTPBodyHealthCheckerDoesNotHangOnEndlessStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Error("response writer does not support flushing")
+			return
+		}
+		for {
+			if _, err := w.Write([]byte("streaming filler data\n")); err != nil {
+				return // Client gave up
+			}
+			flusher.Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	server, err := model.NewBackendServer("endless", ts.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+
+	checker := NewHTTPBodyHealthChecker(500*time.Millisecond, "", "never-appears")
+	done := make(chan error, 1)
+	go func() { done <- checker.Check(server) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected the check to fail against an endless stream")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("health check hung on an endlessly streaming body")
+	}
+}
+
+func TestHealthCheckPathResolution(t *testing.T) {
+	httpServer, _ := model.NewBackendServer("scheme-http", "http://localhost:9001", 1)
+	httpsServer, _ := model.NewBackendServer("scheme-https", "https://localhost:9002", 1)
+	customServer, _ := model.NewBackendServer("scheme-custom", "http://localhost:9003", 1)
+	if err := customServer.SetHealthCheckEndpoint("http://localhost:9003/internal/healthz", 0); err != nil {
+		t.Fatalf("failed to set health endpoint: %v", err)
+	}
+
+	cfg := config.HealthCheckConfig{Path: "/health", HTTPSPath: "/secure-health"}
+	if got := healthCheckPath(httpServer, cfg); got != "/health" {
+		t.Errorf("expected /health for an HTTP backend, got %q", got)
+	}
+	if got := healthCheckPath(httpsServer, cfg); got != "/secure-health" {
+		t.Errorf("expected /secure-health for an HTTPS backend, got %q", got)
+	}
+	// A health URL with its own path takes precedence; nothing is appended
+	if got := healthCheckPath(customServer, cfg); got != "" {
+		t.Errorf("expected no extra path for a backend with a health URL path, got %q", got)
+	}
+}
+
+func TestHTTPHealthCheckerUsesCustomPath(t *testing.T) {
+	var gotPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	server, _ := model.NewBackendServer("custom-path", ts.URL, 1)
+	checker := NewHTTPHealthChecker(2*time.Second, "/internal/healthz")
+	if err := checker.Check(server); err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+	if gotPath != "/internal/healthz" {
+		t.Errorf("expected probe at /internal/healthz, got %q", gotPath)
+	}
+}
+
+func TestTCPBackendIgnoresHealthPath(t *testing.T) {
+	ln := startBannerServer(t, "")
+	defer ln.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, _ := model.NewBackendServer("tcp-nopath", "tcp://"+ln.Addr().String(), 1)
+	pool.AddServer(server)
+
+	// A configured HTTP path must not break TCP checks
+	cfg := config.HealthCheckConfig{TimeoutSeconds: 2, Path: "/health"}
+	checkServer(pool, server, cfg, zap.NewNop())
+	if !server.IsAlive() {
+		t.Error("expected the TCP backend healthy despite a configured HTTP path")
+	}
+}
+
+func TestCooldownDelaysRecovery(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, _ := model.NewBackendServer("cooldown", ts.URL, 1)
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	cfg := config.HealthCheckConfig{TimeoutSeconds: 2, CooldownSeconds: 1}
+	log := zap.NewNop()
+
+	// Mark the backend unhealthy, then have checks start passing immediately
+	pool.SetBackendStatus(server.ID, model.UNHEALTHY)
+	checkServer(pool, server, cfg, log)
+	if server.IsAlive() {
+		t.Fatal("server rejoined rotation before the cool-down elapsed")
+	}
+
+	// Once the cool-down has passed, a passing check restores the backend
+	time.Sleep(1100 * time.Millisecond)
+	checkServer(pool, server, cfg, log)
+	if !server.IsAlive() {
+		t.Error("server still out of rotation after the cool-down elapsed")
+	}
+}
+
+func TestUnresolvableHostFailsFast(t *testing.T) {
+	server, err := model.NewBackendServer("dns-1", "http://backend.does-not-exist.invalid:9999", 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	for name, checker := range map[string]Checker{
+		"http": NewHTTPHealthChecker(10*time.Second, "/health"),
+		"tcp":  NewTCPHealthChecker(10 * time.Second),
+	} {
+		start := time.Now()
+		if err := checker.Check(server); err == nil {
+			t.Errorf("%s: expected check against unresolvable host to fail", name)
+		}
+		if elapsed := time.Since(start); elapsed > 3*time.Second {
+			t.Errorf("%s: expected fast DNS failure, check took %v", name, elapsed)
+		}
+	}
+}
+
+func TestRedirectingBackendFailsCheckByDefault(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer redirecting.Close()
+
+	server, err := model.NewBackendServer("redir-1", redirecting.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	checker := NewHTTPHealthChecker(time.Second, "/health")
+	if err := checker.Check(server); err == nil {
+		t.Error("expected a 302 response to fail the check by default")
+	}
+
+	checker.SetRedirectPolicy("follow", 0)
+	if err := checker.Check(server); err != nil {
+		t.Errorf("expected the check to pass when following redirects, got %v", err)
+	}
+}
+
+// stubChecker returns a fixed result, for composing composite check tests.
+type stubChecker struct{ err error }
+
+func (s stubChecker) Check(*model.BackendServer) error { return s.err }
+
+func TestCompositeCheckerAllSemantics(t *testing.T) {
+	server, err := model.NewBackendServer("comp-1", "http://localhost:9999", 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	allPass := NewCompositeChecker(true, stubChecker{}, stubChecker{})
+	if err := allPass.Check(server); err != nil {
+		t.Errorf("expected all-pass composite to pass, got %v", err)
+	}
+
+	oneFail := NewCompositeChecker(true, stubChecker{}, stubChecker{err: errors.New("tcp refused")})
+	if err := oneFail.Check(server); err == nil {
+		t.Error("expected AND composite to fail when one sub-check fails")
+	}
+}
+
+func TestCompositeCheckerAnySemantics(t *testing.T) {
+	server, err := model.NewBackendServer("comp-2", "http://localhost:9999", 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	onePass := NewCompositeChecker(false, stubChecker{err: errors.New("http 500")}, stubChecker{})
+	if err := onePass.Check(server); err != nil {
+		t.Errorf("expected OR composite to pass with one passing sub-check, got %v", err)
+	}
+
+	allFail := NewCompositeChecker(false, stubChecker{err: errors.New("http 500")}, stubChecker{err: errors.New("tcp refused")})
+	if err := allFail.Check(server); err == nil {
+		t.Error("expected OR composite to fail when every sub-check fails")
+	}
+}
+
+func TestHealthCheckStatsIncrement(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("stats-1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	pool.AddServer(server)
+
+	cfg := config.HealthCheckConfig{TimeoutSeconds: 1}
+	checkServer(pool, server, cfg, zap.NewNop())
+	checkServer(pool, server, cfg, zap.NewNop())
+
+	stats := server.GetHealthCheckStats()
+	if stats.ChecksRun != 2 {
+		t.Errorf("expected 2 checks run, got %d", stats.ChecksRun)
+	}
+	if stats.Failures != 0 {
+		t.Errorf("expected no failures, got %d", stats.Failures)
+	}
+
+	// A failing backend increments the failure counter too.
+	backend.Close()
+	checkServer(pool, server, cfg, zap.NewNop())
+	stats = server.GetHealthCheckStats()
+	if stats.ChecksRun != 3 || stats.Failures != 1 {
+		t.Errorf("expected 3 checks with 1 failure, got %+v", stats)
+	}
+}
+
+// recordingChecker notes which servers it checked.
+type recordingChecker struct {
+	checked []string
+}
+
+func (rc *recordingChecker) Check(server *model.BackendServer) error {
+	rc.checked = append(rc.checked, server.ID)
+	return nil
+}
+
+func TestRegisteredCheckerUsedForCustomScheme(t *testing.T) {
+	recorder := &recordingChecker{}
+	RegisterChecker("fake", func(server *model.BackendServer, cfg config.HealthCheckConfig) Checker {
+		return recorder
+	})
+	defer func() {
+		checkerRegistryMu.Lock()
+		delete(checkerRegistry, "fake")
+		checkerRegistryMu.Unlock()
+	}()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("custom-1", "fake://localhost:1234", 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	pool.AddServer(server)
+
+	checkServer(pool, server, config.HealthCheckConfig{TimeoutSeconds: 1}, zap.NewNop())
+	if len(recorder.checked) != 1 || recorder.checked[0] != "custom-1" {
+		t.Errorf("expected the registered checker to be used, checked: %v", recorder.checked)
+	}
+	if server.HealthStatus != model.HEALTHY {
+		t.Errorf("expected the passing custom check to mark the server healthy, got %s", server.HealthStatus)
+	}
+}
+
+func TestInitialCheckPassRunsBeforeFirstInterval(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("boot-1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	pool.AddServer(server) // Starts UNHEALTHY until a check passes
+
+	// An interval far longer than the test: only the startup pass can flip it
+	StartHealthChecks(pool, config.HealthCheckConfig{IntervalSeconds: 300, TimeoutSeconds: 1}, zap.NewNop())
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if server.HealthStatus == model.HEALTHY {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("expected the startup pass to mark the backend healthy before the first interval, status %s", server.HealthStatus)
+}
+
+func TestForceCheckerOverridesScheme(t *testing.T) {
+	// A backend that accepts TCP connections but 500s every HTTP request:
+	// only a forced TCP check can see it as healthy.
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("forced-1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	pool.AddServer(server)
+
+	cfg := config.HealthCheckConfig{TimeoutSeconds: 1, ForceChecker: "tcp"}
+	checkServer(pool, server, cfg, zap.NewNop())
+	if server.HealthStatus != model.HEALTHY {
+		t.Errorf("expected forced TCP check to pass against an erroring HTTP backend, got %s", server.HealthStatus)
+	}
+
+	// Without the override the HTTP checker sees the 500 and fails
+	checkServer(pool, server, config.HealthCheckConfig{TimeoutSeconds: 1}, zap.NewNop())
+	if server.HealthStatus != model.UNHEALTHY {
+		t.Errorf("expected default HTTP check to fail against a 500ing backend, got %s", server.HealthStatus)
+	}
+}
+
+func TestTLSHealthCheckerHandshakeSucceeds(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	server, err := model.NewBackendServer("tls-1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	// Self-signed test certificate: verification must be skipped
+	checker := NewTLSHealthChecker(time.Second, true)
+	if err := checker.Check(server); err != nil {
+		t.Errorf("expected handshake against a TLS listener to succeed, got %v", err)
+	}
+	if server.GetResponseTimeMicros() <= 0 {
+		t.Error("expected handshake latency to be recorded")
+	}
+
+	// With verification on, the self-signed certificate fails the check
+	strict := NewTLSHealthChecker(time.Second, false)
+	if err := strict.Check(server); err == nil {
+		t.Error("expected verification of a self-signed certificate to fail")
+	}
+}
+
+func TestTLSHealthCheckerFailsAgainstPlainTCP(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	server, err := model.NewBackendServer("plain-1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	checker := NewTLSHealthChecker(time.Second, true)
+	if err := checker.Check(server); err == nil {
+		t.Error("expected handshake against a plain TCP listener to fail")
+	}
+}
+
+func TestHealthCheckBearerTokenSent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sekrit" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	server, err := model.NewBackendServer("auth-bearer", ts.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+
+	cfg := config.HealthCheckConfig{TimeoutSeconds: 2, Auth: config.HealthCheckAuthConfig{BearerToken: "sekrit"}}
+	checker := checkerFor(server, cfg)
+	if err := checker.Check(server); err != nil {
+		t.Errorf("expected check with bearer token to pass, got %v", err)
+	}
+}
+
+func TestHealthCheckBasicAuthSent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "probe" || pass != "hunter2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	server, err := model.NewBackendServer("auth-basic", ts.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+
+	cfg := config.HealthCheckConfig{TimeoutSeconds: 2, Auth: config.HealthCheckAuthConfig{Username: "probe", Password: "hunter2"}}
+	checker := checkerFor(server, cfg)
+	if err := checker.Check(server); err != nil {
+		t.Errorf("expected check with basic auth to pass, got %v", err)
+	}
+}
+
+func TestHealthCheckUnauthorizedMarksUnhealthy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	server, err := model.NewBackendServer("auth-denied", ts.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	server.SetStatus(model.HEALTHY)
+	pool.AddServer(server)
+
+	// Wrong token: the backend's 401 must fail the check and eject the server
+	cfg := config.HealthCheckConfig{TimeoutSeconds: 2, Auth: config.HealthCheckAuthConfig{BearerToken: "wrong"}}
+	checkServer(pool, server, cfg, zap.NewNop())
+	if server.IsAlive() {
+		t.Error("expected server to be marked unhealthy after a 401 health response")
+	}
+}
@@ -66,6 +66,45 @@ func NewWeightedRoundRobinAlgorithm() *WeightedRoundRobinAlgorithm {
 	}
 }
 
+// Reset clears the accumulated smooth-weight state so the next selection cycle
+// starts deterministically, as if the algorithm were freshly created.
+func (wrr *WeightedRoundRobinAlgorithm) Reset() {
+	wrr.mu.Lock()
+	defer wrr.mu.Unlock()
+	wrr.currentWeights = make(map[string]int)
+}
+
+// RemoveServer purges the smooth-weight entry for a server leaving the pool,
+// so removed servers neither leak memory nor skew future totals.
+func (wrr *WeightedRoundRobinAlgorithm) RemoveServer(id string) {
+	wrr.mu.Lock()
+	defer wrr.mu.Unlock()
+	delete(wrr.currentWeights, id)
+}
+
+// gcd returns the greatest common divisor of two non-negative ints.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// weightGCD returns the GCD across the positive weights in the pool, used to
+// normalize weights like 1000:2000 down to 1:2 before the smooth-weight math.
+func weightGCD(backends []*model.BackendServer) int {
+	g := 0
+	for _, server := range backends {
+		if server.Weight > 0 {
+			g = gcd(g, server.Weight)
+		}
+	}
+	if g == 0 {
+		return 1
+	}
+	return g
+}
+
 func (wrr *WeightedRoundRobinAlgorithm) Select(backends []*model.BackendServer, req *http.Request, current uint64) *model.BackendServer {
 	if len(backends) == 0 {
 		return nil
@@ -81,6 +120,10 @@ func (wrr *WeightedRoundRobinAlgorithm) Select(backends []*model.BackendServer,
 		}
 	}
 
+	// Normalizing by the GCD keeps selection order identical while bounding
+	// the internal counters for arbitrarily large operator-chosen weights.
+	divisor := weightGCD(backends)
+
 	// Find total weight and max current weight
 	totalWeight := 0
 	maxCurrentWeight := 0
@@ -90,10 +133,11 @@ func (wrr *WeightedRoundRobinAlgorithm) Select(backends []*model.BackendServer,
 		if server.Weight <= 0 {
 			continue // Skip servers with zero or negative weight
 		}
-		
-		totalWeight += server.Weight
-		wrr.currentWeights[server.ID] += server.Weight
-		
+
+		weight := server.Weight / divisor
+		totalWeight += weight
+		wrr.currentWeights[server.ID] += weight
+
 		if selectedServer == nil || wrr.currentWeights[server.ID] > maxCurrentWeight {
 			maxCurrentWeight = wrr.currentWeights[server.ID]
 			selectedServer = server
@@ -101,8 +145,9 @@ func (wrr *WeightedRoundRobinAlgorithm) Select(backends []*model.BackendServer,
 	}
 
 	if selectedServer == nil {
-		// No servers with positive weight, fall back to first server
-		return backends[0]
+		// Every eligible backend has zero weight (e.g. all draining); report
+		// no selection rather than routing onto a drained server.
+		return nil
 	}
 
 	// Decrease the selected server's current weight by total weight
@@ -111,6 +156,47 @@ func (wrr *WeightedRoundRobinAlgorithm) Select(backends []*model.BackendServer,
 	return selectedServer
 }
 
+// ZoneAffinityAlgorithm wraps another algorithm with a same-zone preference:
+// when healthy backends exist in the load balancer's own zone, selection is
+// restricted to them, and only crosses zones when the local zone is empty.
+// Backends without a zone are treated as cross-zone.
+type ZoneAffinityAlgorithm struct {
+	zone  string
+	inner LoadBalancingAlgorithm
+}
+
+// NewZoneAffinityAlgorithm wraps inner with a preference for the given zone.
+func NewZoneAffinityAlgorithm(zone string, inner LoadBalancingAlgorithm) *ZoneAffinityAlgorithm {
+	return &ZoneAffinityAlgorithm{zone: zone, inner: inner}
+}
+
+func (za *ZoneAffinityAlgorithm) Select(backends []*model.BackendServer, req *http.Request, current uint64) *model.BackendServer {
+	sameZone := make([]*model.BackendServer, 0, len(backends))
+	for _, server := range backends {
+		if server.Zone == za.zone {
+			sameZone = append(sameZone, server)
+		}
+	}
+	if len(sameZone) > 0 {
+		return za.inner.Select(sameZone, req, current)
+	}
+	return za.inner.Select(backends, req, current)
+}
+
+// Reset forwards to the wrapped algorithm when it holds resettable state.
+func (za *ZoneAffinityAlgorithm) Reset() {
+	if r, ok := za.inner.(interface{ Reset() }); ok {
+		r.Reset()
+	}
+}
+
+// RemoveServer forwards to the wrapped algorithm when it tracks per-server state.
+func (za *ZoneAffinityAlgorithm) RemoveServer(id string) {
+	if r, ok := za.inner.(interface{ RemoveServer(string) }); ok {
+		r.RemoveServer(id)
+	}
+}
+
 // WeightedAlgorithm implements simple weighted random selection (kept for backward compatibility).
 type WeightedAlgorithm struct{}
 
@@ -125,7 +211,8 @@ func (wa *WeightedAlgorithm) Select(backends []*model.BackendServer, req *http.R
 		}
 	}
 	if totalWeight == 0 {
-		return backends[0]
+		// All weights are zero (e.g. every backend draining): no selection.
+		return nil
 	}
 	r := rand.New(rand.NewSource(time.Now().UnixNano())).Intn(totalWeight)
 	for _, s := range backends {
@@ -138,3 +225,51 @@ func (wa *WeightedAlgorithm) Select(backends []*model.BackendServer, req *http.R
 	}
 	return backends[0]
 }
+
+// AdaptiveAlgorithm blends weight, active connections, and smoothed latency
+// into one score per backend and selects the minimum. Scoring weights are
+// configurable; a backend with no latency data yet simply scores without the
+// latency term.
+type AdaptiveAlgorithm struct {
+	ConnectionWeight float64 // Importance of active connections in the score
+	LatencyWeight    float64 // Importance of EWMA latency (per millisecond)
+}
+
+// NewAdaptiveAlgorithm creates an AdaptiveAlgorithm with balanced default
+// scoring weights.
+func NewAdaptiveAlgorithm() *AdaptiveAlgorithm {
+	return &AdaptiveAlgorithm{
+		ConnectionWeight: 1.0,
+		LatencyWeight:    1.0,
+	}
+}
+
+// score computes the load score for a backend; lower is better. The raw load
+// (connections and latency) is divided by the configured weight so heavier
+// backends absorb proportionally more traffic.
+func (aa *AdaptiveAlgorithm) score(server *model.BackendServer) float64 {
+	load := 1.0 // Base cost so an idle, zero-latency backend still scores by weight
+	load += aa.ConnectionWeight * float64(server.GetActiveConnections())
+	load += aa.LatencyWeight * float64(server.GetResponseTimeEWMA())
+
+	weight := server.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+	return load / float64(weight)
+}
+
+func (aa *AdaptiveAlgorithm) Select(backends []*model.BackendServer, req *http.Request, current uint64) *model.BackendServer {
+	if len(backends) == 0 {
+		return nil
+	}
+	selected := backends[0]
+	best := aa.score(selected)
+	for _, server := range backends[1:] {
+		if s := aa.score(server); s < best {
+			best = s
+			selected = server
+		}
+	}
+	return selected
+}
@@ -2,9 +2,11 @@ package loadbalancing
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
 )
@@ -42,3 +44,279 @@ func TestRoundRobinAlgorithm_Select(t *testing.T) {
 		t.Error("expected nil when no backends")
 	}
 }
+
+func TestWeightedRoundRobinResetAfterAlgorithmSwitch(t *testing.T) {
+	req := &http.Request{}
+	backends := makeBackends(2)
+	backends[0].Weight = 3
+	backends[1].Weight = 1
+
+	wrr := NewWeightedRoundRobinAlgorithm()
+	pool := NewServerPool(wrr)
+	pool.AddServer(backends[0])
+	pool.AddServer(backends[1])
+
+	// Advance the weighted algorithm partway through a cycle, then switch
+	// away and back again to the same instance.
+	for i := 0; i < 3; i++ {
+		pool.SelectBackend(req)
+	}
+	pool.SetAlgorithm(&RoundRobinAlgorithm{})
+	pool.SetAlgorithm(wrr)
+
+	// A full cycle after the switch should honor the configured weights exactly.
+	counts := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		backend := pool.SelectBackend(req)
+		if backend == nil {
+			t.Fatal("expected a backend to be selected")
+		}
+		counts[backend.ID]++
+	}
+	if counts["A"] != 3 || counts["B"] != 1 {
+		t.Errorf("expected 3:1 distribution after switch, got %v", counts)
+	}
+}
+
+func TestZeroWeightServerIsNeverSelected(t *testing.T) {
+	algorithms := map[string]LoadBalancingAlgorithm{
+		"roundrobin":       &RoundRobinAlgorithm{},
+		"leastconnections": &LeastConnectionsAlgorithm{},
+		"iphash":           &IPHashAlgorithm{},
+		"weighted":         NewWeightedRoundRobinAlgorithm(),
+		"weightedrandom":   &WeightedAlgorithm{},
+	}
+
+	for name, algo := range algorithms {
+		backends := makeBackends(3)
+		backends[1].Weight = 0 // Drained
+
+		pool := NewServerPool(algo)
+		for _, b := range backends {
+			pool.AddServer(b)
+		}
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.1.2.3:5000"
+		for i := 0; i < 20; i++ {
+			backend := pool.SelectBackend(req)
+			if backend == nil {
+				t.Fatalf("%s: expected a backend to be selected", name)
+			}
+			if backend.ID == backends[1].ID {
+				t.Errorf("%s: drained (weight 0) server was selected", name)
+				break
+			}
+			pool.Next()
+		}
+	}
+}
+
+func TestFractionalWeightsDistributeProportionally(t *testing.T) {
+	// A 1.5:1 config ratio is scaled to integer weights 15:10, which must
+	// distribute traffic approximately 3:2.
+	algo := NewWeightedRoundRobinAlgorithm()
+	req := &http.Request{}
+	backends := makeBackends(2)
+	backends[0].Weight = 15
+	backends[1].Weight = 10
+
+	counts := make(map[string]int)
+	for i := 0; i < 25; i++ {
+		backend := algo.Select(backends, req, uint64(i))
+		if backend == nil {
+			t.Fatal("expected a backend to be selected")
+		}
+		counts[backend.ID]++
+	}
+
+	if counts["A"] != 15 || counts["B"] != 10 {
+		t.Errorf("expected a 15:10 (3:2) distribution, got A=%d B=%d", counts["A"], counts["B"])
+	}
+}
+
+func TestAdaptiveAlgorithm_Select(t *testing.T) {
+	req := &http.Request{}
+	algo := NewAdaptiveAlgorithm()
+
+	// Equal weights: the backend with fewer connections and lower latency wins
+	backends := makeBackends(3)
+	backends[0].IncrementConnections()
+	backends[0].IncrementConnections()
+	backends[0].SetResponseTime(50 * time.Millisecond)
+	backends[1].IncrementConnections()
+	backends[1].SetResponseTime(10 * time.Millisecond)
+	backends[2].IncrementConnections()
+	backends[2].SetResponseTime(200 * time.Millisecond)
+
+	if got := algo.Select(backends, req, 0); got == nil || got.ID != "B" {
+		t.Errorf("expected backend B (low connections, low latency), got %v", got)
+	}
+
+	// A heavier weight absorbs more load for the same latency and connections
+	backends = makeBackends(2)
+	backends[0].Weight = 10
+	backends[0].IncrementConnections()
+	backends[0].SetResponseTime(100 * time.Millisecond)
+	backends[1].Weight = 1
+	backends[1].IncrementConnections()
+	backends[1].SetResponseTime(100 * time.Millisecond)
+	if got := algo.Select(backends, req, 0); got == nil || got.ID != "A" {
+		t.Errorf("expected heavier backend A, got %v", got)
+	}
+
+	// Missing latency data doesn't break scoring
+	backends = makeBackends(2)
+	backends[0].SetResponseTime(500 * time.Millisecond)
+	if got := algo.Select(backends, req, 0); got == nil || got.ID != "B" {
+		t.Errorf("expected backend B with no latency data to score better, got %v", got)
+	}
+
+	if algo.Select([]*model.BackendServer{}, req, 0) != nil {
+		t.Error("expected nil when no backends")
+	}
+}
+
+func TestAdaptiveAlgorithm_ScoringWeights(t *testing.T) {
+	req := &http.Request{}
+	backends := makeBackends(2)
+	// A: many connections, low latency. B: few connections, high latency.
+	for i := 0; i < 10; i++ {
+		backends[0].IncrementConnections()
+	}
+	backends[0].SetResponseTime(10 * time.Millisecond)
+	backends[1].IncrementConnections()
+	backends[1].SetResponseTime(300 * time.Millisecond)
+
+	// Latency-dominated scoring prefers A despite its connection count
+	latencyBiased := &AdaptiveAlgorithm{ConnectionWeight: 0.01, LatencyWeight: 1.0}
+	if got := latencyBiased.Select(backends, req, 0); got == nil || got.ID != "A" {
+		t.Errorf("expected latency-biased scoring to pick A, got %v", got)
+	}
+
+	// Connection-dominated scoring prefers B despite its latency
+	connBiased := &AdaptiveAlgorithm{ConnectionWeight: 100.0, LatencyWeight: 0.01}
+	if got := connBiased.Select(backends, req, 0); got == nil || got.ID != "B" {
+		t.Errorf("expected connection-biased scoring to pick B, got %v", got)
+	}
+}
+
+func TestWeightedRoundRobinNormalizesLargeWeights(t *testing.T) {
+	large := []*model.BackendServer{
+		{ID: "a", Weight: 1000, HealthStatus: model.HEALTHY},
+		{ID: "b", Weight: 2000, HealthStatus: model.HEALTHY},
+	}
+	small := []*model.BackendServer{
+		{ID: "a", Weight: 1, HealthStatus: model.HEALTHY},
+		{ID: "b", Weight: 2, HealthStatus: model.HEALTHY},
+	}
+
+	largeAlgo := NewWeightedRoundRobinAlgorithm()
+	smallAlgo := NewWeightedRoundRobinAlgorithm()
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	// 1000:2000 must produce the same selection sequence as 1:2
+	for i := 0; i < 30; i++ {
+		gotLarge := largeAlgo.Select(large, req, 0)
+		gotSmall := smallAlgo.Select(small, req, 0)
+		if gotLarge.ID != gotSmall.ID {
+			t.Fatalf("selection %d diverged: 1000:2000 chose %s, 1:2 chose %s", i, gotLarge.ID, gotSmall.ID)
+		}
+	}
+
+	// Normalization bounds the internal counters to the reduced weights
+	largeAlgo.mu.RLock()
+	defer largeAlgo.mu.RUnlock()
+	for id, cw := range largeAlgo.currentWeights {
+		if cw < -3 || cw > 3 {
+			t.Errorf("expected normalized counter for %s within [-3, 3], got %d", id, cw)
+		}
+	}
+}
+
+func TestWeightGCD(t *testing.T) {
+	tests := []struct {
+		weights []int
+		want    int
+	}{
+		{[]int{1000, 1500, 2000}, 500},
+		{[]int{1, 2}, 1},
+		{[]int{7}, 7},
+		{[]int{0, 0}, 1},
+	}
+	for _, tc := range tests {
+		backends := make([]*model.BackendServer, len(tc.weights))
+		for i, w := range tc.weights {
+			backends[i] = &model.BackendServer{Weight: w}
+		}
+		if got := weightGCD(backends); got != tc.want {
+			t.Errorf("weightGCD(%v) = %d, want %d", tc.weights, got, tc.want)
+		}
+	}
+}
+
+func TestZoneAffinityPrefersSameZone(t *testing.T) {
+	backends := []*model.BackendServer{
+		{ID: "local-1", Zone: "us-east-1a", HealthStatus: model.HEALTHY, Weight: 1},
+		{ID: "local-2", Zone: "us-east-1a", HealthStatus: model.HEALTHY, Weight: 1},
+		{ID: "remote-1", Zone: "us-east-1b", HealthStatus: model.HEALTHY, Weight: 1},
+	}
+	algo := NewZoneAffinityAlgorithm("us-east-1a", &RoundRobinAlgorithm{})
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	for i := 0; i < 20; i++ {
+		got := algo.Select(backends, req, uint64(i))
+		if got.Zone != "us-east-1a" {
+			t.Fatalf("selection %d crossed zones while same-zone backends were healthy: %s", i, got.ID)
+		}
+	}
+}
+
+func TestZoneAffinityFallsBackAcrossZones(t *testing.T) {
+	backends := []*model.BackendServer{
+		{ID: "remote-1", Zone: "us-east-1b", HealthStatus: model.HEALTHY, Weight: 1},
+		{ID: "remote-2", Zone: "us-east-1c", HealthStatus: model.HEALTHY, Weight: 1},
+	}
+	algo := NewZoneAffinityAlgorithm("us-east-1a", &RoundRobinAlgorithm{})
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	seen := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		got := algo.Select(backends, req, uint64(i))
+		if got == nil {
+			t.Fatal("expected cross-zone fallback to select a backend")
+		}
+		seen[got.ID] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("expected fallback to rotate across both remote backends, saw %v", seen)
+	}
+}
+
+func TestWeightedAlgorithmsReturnNilWhenAllWeightsZero(t *testing.T) {
+	a, _ := model.NewBackendServer("drain-1", "http://localhost:9001", 1)
+	b, _ := model.NewBackendServer("drain-2", "http://localhost:9002", 1)
+	a.Weight = 0
+	b.Weight = 0
+	backends := []*model.BackendServer{a, b}
+	req := httptest.NewRequest("GET", "/", nil)
+
+	wrr := NewWeightedRoundRobinAlgorithm()
+	if got := wrr.Select(backends, req, 0); got != nil {
+		t.Errorf("expected nil from WRR when all weights are zero, got %s", got.ID)
+	}
+
+	wa := &WeightedAlgorithm{}
+	if got := wa.Select(backends, req, 0); got != nil {
+		t.Errorf("expected nil from WeightedAlgorithm when all weights are zero, got %s", got.ID)
+	}
+
+	// A single positive weight restores selection
+	b.Weight = 2
+	if got := wrr.Select(backends, req, 0); got == nil || got.ID != "drain-2" {
+		t.Errorf("expected WRR to pick the only weighted backend, got %v", got)
+	}
+	if got := wa.Select(backends, req, 0); got == nil || got.ID != "drain-2" {
+		t.Errorf("expected WeightedAlgorithm to pick the only weighted backend, got %v", got)
+	}
+}
@@ -1,7 +1,9 @@
 package loadbalancing
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,62 +20,114 @@ type LoadBalancingAlgorithm interface {
 }
 
 type ServerPool struct {
-	backends     []*model.BackendServer
-	current      uint64 // For Round Robin, or other algorithm state
-	algorithm    LoadBalancingAlgorithm
-	mu           sync.RWMutex // Protects 'backends' slice
-	
+	backends    []*model.BackendServer
+	current     uint64 // For Round Robin, or other algorithm state
+	algorithm   LoadBalancingAlgorithm
+	maxBackends int          // Maximum pool size; 0 means unlimited. Guarded by mu.
+	mu          sync.RWMutex // Protects 'backends' slice
+	healthy     atomic.Value // Cached []*model.BackendServer snapshot of healthy backends
+
 	// Request metrics
 	totalRequests    uint64 // Total requests processed
-	requestsLastMin  uint64 // Requests in the last minute (for RPS calculation)
-	lastResetTime    int64  // Last time we reset the per-minute counter
-	
+	inFlightRequests int64  // Requests currently being proxied
+
+	// Sliding-window RPS: one bucket per second, stale buckets age out so the
+	// reported rate decays to zero promptly once traffic stops.
+	rpsMu      sync.Mutex
+	rpsBuckets [rpsWindowSeconds]uint64
+	rpsStamps  [rpsWindowSeconds]int64
+	nowFunc    func() time.Time // Overridable clock for tests; nil means time.Now
+
 	// Geographic tracking
-	geoTracker *geographic.GeographicTracker
+	geoTracker       *geographic.GeographicTracker
+	geoSampleRate    uint64 // Track 1 in N requests; 0 or 1 means every request
+	geoSampleCounter uint64 // Rolling counter used to pick sampled requests
+
+	// Selection latency: a ring of recent SelectBackend durations, so a
+	// pathological algorithm shows up in metrics before it hurts tail latency.
+	selMu        sync.Mutex
+	selDurations [selectionLatencySamples]time.Duration
+	selCount     uint64 // Total selections recorded; ring index is selCount % len
 }
 
 // NewServerPool creates a new ServerPool.
 func NewServerPool(algo LoadBalancingAlgorithm) *ServerPool {
 	return &ServerPool{
-		backends:      make([]*model.BackendServer, 0),
-		algorithm:     algo,
-		lastResetTime: time.Now().Unix(),
-		geoTracker:    nil, // Will be initialized when logger is available
+		backends:   make([]*model.BackendServer, 0),
+		algorithm:  algo,
+		geoTracker: nil, // Will be initialized when logger is available
 	}
 }
 
 // NewServerPoolWithLogger creates a new ServerPool with geographic tracking enabled.
 func NewServerPoolWithLogger(algo LoadBalancingAlgorithm, logger *zap.Logger) *ServerPool {
 	return &ServerPool{
-		backends:      make([]*model.BackendServer, 0),
-		algorithm:     algo,
-		lastResetTime: time.Now().Unix(),
-		geoTracker:    geographic.NewGeographicTracker(logger),
+		backends:   make([]*model.BackendServer, 0),
+		algorithm:  algo,
+		geoTracker: geographic.NewGeographicTracker(logger),
 	}
 }
 
-// AddServer adds a backend server to the pool.
-func (sp *ServerPool) AddServer(server *model.BackendServer) {
+// SetMaxBackends caps the pool size; adding beyond the cap fails. A value of
+// zero or less means unlimited, the default.
+func (sp *ServerPool) SetMaxBackends(n int) {
 	sp.mu.Lock()
 	defer sp.mu.Unlock()
+	sp.maxBackends = n
+}
+
+// AddServer adds a backend server to the pool, ignoring the pool size cap
+// check result. Prefer TryAddServer where the caller can surface the error.
+func (sp *ServerPool) AddServer(server *model.BackendServer) {
+	_ = sp.TryAddServer(server)
+}
+
+// MaxBackends reports the configured pool size cap; 0 means unlimited.
+func (sp *ServerPool) MaxBackends() int {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	return sp.maxBackends
+}
+
+// TryAddServer adds a backend server to the pool, failing if the configured
+// maximum pool size would be exceeded.
+func (sp *ServerPool) TryAddServer(server *model.BackendServer) error {
+	sp.mu.Lock()
+	if sp.maxBackends > 0 && len(sp.backends) >= sp.maxBackends {
+		max := sp.maxBackends
+		sp.mu.Unlock()
+		return fmt.Errorf("backend pool is full: maxBackends limit of %d reached", max)
+	}
 	sp.backends = append(sp.backends, server)
+	sp.mu.Unlock()
+	sp.RefreshHealthySnapshot()
+	return nil
 }
 
 // RemoveServer removes a backend server from the pool by ID.
 func (sp *ServerPool) RemoveServer(serverID string) bool {
 	sp.mu.Lock()
-	defer sp.mu.Unlock()
 	for i, server := range sp.backends {
 		if server.ID == serverID {
 			sp.backends = append(sp.backends[:i], sp.backends[i+1:]...)
+			algo := sp.algorithm
+			sp.mu.Unlock()
+			// Purge any per-server algorithm state so removed servers don't
+			// leak entries or skew future selections.
+			if h, ok := algo.(serverRemovalAware); ok {
+				h.RemoveServer(serverID)
+			}
+			sp.RefreshHealthySnapshot()
 			return true
 		}
 	}
+	sp.mu.Unlock()
 	return false
 }
 
-// GetHealthyServers returns a list of currently healthy backend servers.
-func (sp *ServerPool) GetHealthyServers() []*model.BackendServer {
+// filterHealthyServers walks the backend list under the lock and returns the
+// currently healthy servers. This is the slow path behind the cached snapshot.
+func (sp *ServerPool) filterHealthyServers() []*model.BackendServer {
 	sp.mu.RLock()
 	defer sp.mu.RUnlock()
 	healthy := make([]*model.BackendServer, 0)
@@ -85,6 +139,22 @@ func (sp *ServerPool) GetHealthyServers() []*model.BackendServer {
 	return healthy
 }
 
+// RefreshHealthySnapshot rebuilds the cached healthy-backend snapshot. It is
+// called automatically when pool membership or a backend's status changes via
+// the pool; call it directly if a backend's health is mutated out of band.
+func (sp *ServerPool) RefreshHealthySnapshot() {
+	sp.healthy.Store(sp.filterHealthyServers())
+}
+
+// GetHealthyServers returns the cached snapshot of currently healthy backend
+// servers. Callers must not mutate the returned slice.
+func (sp *ServerPool) GetHealthyServers() []*model.BackendServer {
+	if snapshot, ok := sp.healthy.Load().([]*model.BackendServer); ok {
+		return snapshot
+	}
+	return sp.filterHealthyServers()
+}
+
 // GetServers returns all backend servers (healthy or not).
 func (sp *ServerPool) GetServers() []*model.BackendServer {
 	sp.mu.RLock()
@@ -94,9 +164,130 @@ func (sp *ServerPool) GetServers() []*model.BackendServer {
 	return servers
 }
 
+// GetServersByTag returns all backend servers carrying the given tag. An
+// empty value matches any value for the key.
+func (sp *ServerPool) GetServersByTag(key, value string) []*model.BackendServer {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	matched := make([]*model.BackendServer, 0)
+	for _, s := range sp.backends {
+		if s.MatchesTag(key, value) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// selectableBackends filters out drained backends (weight <= 0) so a zero
+// weight excludes a server from new selections regardless of the active
+// algorithm. Sticky sessions keep working for drained servers.
+func selectableBackends(backends []*model.BackendServer) []*model.BackendServer {
+	drained := false
+	for _, s := range backends {
+		if s.Weight <= 0 {
+			drained = true
+			break
+		}
+	}
+	if !drained {
+		return backends
+	}
+	selectable := make([]*model.BackendServer, 0, len(backends))
+	for _, s := range backends {
+		if s.Weight > 0 {
+			selectable = append(selectable, s)
+		}
+	}
+	return selectable
+}
+
 // Select a backend using the configured algorithm.
 func (sp *ServerPool) SelectBackend(req *http.Request) *model.BackendServer {
-	return sp.algorithm.Select(sp.GetHealthyServers(), req, sp.current) // Pass current for RR
+	sp.mu.RLock()
+	algo := sp.algorithm
+	sp.mu.RUnlock()
+	start := time.Now()
+	backend := algo.Select(selectableBackends(sp.GetHealthyServers()), req, atomic.LoadUint64(&sp.current)) // Pass current for RR
+	sp.recordSelectionLatency(time.Since(start))
+	return backend
+}
+
+// selectionLatencySamples sizes the ring of recent selection durations used
+// for the latency metric.
+const selectionLatencySamples = 512
+
+// recordSelectionLatency stores one SelectBackend duration in the ring.
+func (sp *ServerPool) recordSelectionLatency(d time.Duration) {
+	sp.selMu.Lock()
+	sp.selDurations[sp.selCount%selectionLatencySamples] = d
+	sp.selCount++
+	sp.selMu.Unlock()
+}
+
+// GetSelectionLatency returns the average and p99 of recent backend selection
+// durations in microseconds, plus how many selections the window holds.
+func (sp *ServerPool) GetSelectionLatency() (avgMicros, p99Micros float64, samples int) {
+	sp.selMu.Lock()
+	n := int(sp.selCount)
+	if n > selectionLatencySamples {
+		n = selectionLatencySamples
+	}
+	window := make([]time.Duration, n)
+	copy(window, sp.selDurations[:n])
+	sp.selMu.Unlock()
+
+	if n == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+	var total time.Duration
+	for _, d := range window {
+		total += d
+	}
+	avgMicros = float64(total.Microseconds()) / float64(n)
+	p99Micros = float64(window[(n*99)/100].Microseconds())
+	return avgMicros, p99Micros, n
+}
+
+// Empty-selection causes, distinguishing why no backend was available so the
+// 503 and metrics can say more than "service unavailable".
+const (
+	EmptyCauseNone           = ""                // Healthy backends exist
+	EmptyCauseNoBackends     = "no_backends"     // Pool has no servers configured
+	EmptyCauseAllMaintenance = "all_maintenance" // Every server is in maintenance mode
+	EmptyCauseAllUnhealthy   = "all_unhealthy"   // Servers exist but none are healthy
+	EmptyCauseAllDrained     = "all_drained"     // Healthy servers exist but every weight is zero
+)
+
+// EmptySelectionCause classifies why the pool has nothing to select, or
+// EmptyCauseNone when healthy backends exist.
+func (sp *ServerPool) EmptySelectionCause() string {
+	servers := sp.GetServers()
+	if len(servers) == 0 {
+		return EmptyCauseNoBackends
+	}
+	maintenance, drained := 0, 0
+	for _, server := range servers {
+		if server.IsAlive() {
+			if server.Weight <= 0 {
+				// Healthy but weight zero: weighted algorithms skip it
+				// entirely (the drain idiom), so it can't be selected.
+				drained++
+				continue
+			}
+			return EmptyCauseNone
+		}
+		if server.HealthStatus == model.MAINTENANCE {
+			maintenance++
+		}
+	}
+	if drained > 0 {
+		return EmptyCauseAllDrained
+	}
+	if maintenance == len(servers) {
+		return EmptyCauseAllMaintenance
+	}
+	return EmptyCauseAllUnhealthy
 }
 
 // Next increments the Round Robin counter
@@ -104,30 +295,108 @@ func (sp *ServerPool) Next() {
 	atomic.AddUint64(&sp.current, 1)
 }
 
+// GetRotationCount returns the current Round Robin counter value.
+func (sp *ServerPool) GetRotationCount() uint64 {
+	return atomic.LoadUint64(&sp.current)
+}
+
 // SetBackendStatus updates the health status of a specific backend.
 func (sp *ServerPool) SetBackendStatus(serverID string, status model.HealthStatus) {
+	sp.SetBackendStatusWithReason(serverID, status, "")
+}
+
+// SetBackendStatusWithReason is SetBackendStatus with a cause recorded in the
+// backend's health transition history.
+func (sp *ServerPool) SetBackendStatusWithReason(serverID string, status model.HealthStatus, reason string) {
 	sp.mu.RLock() // Use RLock first to find, then Lock for update if needed
 	for _, server := range sp.backends {
 		if server.ID == serverID {
 			sp.mu.RUnlock()
-			server.SetStatus(status)
+			server.SetStatusWithReason(status, reason)
+			sp.RefreshHealthySnapshot()
 			return
 		}
 	}
 	sp.mu.RUnlock()
 }
 
-// SetAlgorithm sets the load balancing algorithm for the pool.
+// serverRemovalAware is implemented by algorithms that keep per-server state
+// and must drop it when a server leaves the pool.
+type serverRemovalAware interface {
+	RemoveServer(id string)
+}
+
+// resettableAlgorithm is implemented by algorithms that carry accumulated
+// selection state (e.g. weighted round robin) and can clear it.
+type resettableAlgorithm interface {
+	Reset()
+}
+
+// SetAlgorithm sets the load balancing algorithm for the pool. Algorithms
+// carrying accumulated state are reset so the switch starts from a clean slate.
 func (sp *ServerPool) SetAlgorithm(algo LoadBalancingAlgorithm) {
+	if r, ok := algo.(resettableAlgorithm); ok {
+		r.Reset()
+	}
 	sp.mu.Lock()
 	defer sp.mu.Unlock()
 	sp.algorithm = algo
 }
 
-// IncrementRequestCount increments the total request count and per-minute counter
+// ResetAlgorithmState clears any accumulated selection state (e.g. smooth
+// weighted round robin counters) so weight changes take effect cleanly.
+func (sp *ServerPool) ResetAlgorithmState() {
+	sp.mu.RLock()
+	algo := sp.algorithm
+	sp.mu.RUnlock()
+	if r, ok := algo.(resettableAlgorithm); ok {
+		r.Reset()
+	}
+	sp.RefreshHealthySnapshot()
+}
+
+// IncrementInFlight records the start of a proxied request and returns the
+// new in-flight count.
+func (sp *ServerPool) IncrementInFlight() int64 {
+	return atomic.AddInt64(&sp.inFlightRequests, 1)
+}
+
+// DecrementInFlight records the end of a proxied request.
+func (sp *ServerPool) DecrementInFlight() {
+	atomic.AddInt64(&sp.inFlightRequests, -1)
+}
+
+// GetInFlightRequests returns the number of requests currently being proxied.
+func (sp *ServerPool) GetInFlightRequests() int64 {
+	return atomic.LoadInt64(&sp.inFlightRequests)
+}
+
+// rpsWindowSeconds is the sliding window the requests-per-second figure is
+// averaged over.
+const rpsWindowSeconds = 10
+
+// now returns the pool's clock, defaulting to time.Now.
+func (sp *ServerPool) now() time.Time {
+	if sp.nowFunc != nil {
+		return sp.nowFunc()
+	}
+	return time.Now()
+}
+
+// IncrementRequestCount increments the total request count and the
+// sliding-window RPS buckets.
 func (sp *ServerPool) IncrementRequestCount() {
 	atomic.AddUint64(&sp.totalRequests, 1)
-	atomic.AddUint64(&sp.requestsLastMin, 1)
+
+	nowSec := sp.now().Unix()
+	idx := nowSec % rpsWindowSeconds
+	sp.rpsMu.Lock()
+	if sp.rpsStamps[idx] != nowSec {
+		sp.rpsBuckets[idx] = 0
+		sp.rpsStamps[idx] = nowSec
+	}
+	sp.rpsBuckets[idx]++
+	sp.rpsMu.Unlock()
 }
 
 // GetTotalRequests returns the total number of requests processed
@@ -135,39 +404,50 @@ func (sp *ServerPool) GetTotalRequests() uint64 {
 	return atomic.LoadUint64(&sp.totalRequests)
 }
 
-// GetRequestsPerSecond calculates and returns the current requests per second
+// GetRequestsPerSecond returns the request rate averaged over the sliding
+// window. Buckets older than the window are ignored, so the value ramps down
+// to zero within rpsWindowSeconds once traffic stops.
 func (sp *ServerPool) GetRequestsPerSecond() float64 {
-	now := time.Now().Unix()
-	lastReset := atomic.LoadInt64(&sp.lastResetTime)
-	
-	// If more than 60 seconds have passed, reset the counter
-	if now-lastReset >= 60 {
-		if atomic.CompareAndSwapInt64(&sp.lastResetTime, lastReset, now) {
-			// Reset the per-minute counter
-			atomic.StoreUint64(&sp.requestsLastMin, 0)
-			return 0.0
+	nowSec := sp.now().Unix()
+	var total uint64
+	sp.rpsMu.Lock()
+	for i := range sp.rpsBuckets {
+		if nowSec-sp.rpsStamps[i] < rpsWindowSeconds {
+			total += sp.rpsBuckets[i]
 		}
 	}
-	
-	// Calculate RPS based on requests in the current minute
-	requestsInMin := atomic.LoadUint64(&sp.requestsLastMin)
-	elapsedSeconds := now - lastReset
-	if elapsedSeconds > 0 {
-		return float64(requestsInMin) / float64(elapsedSeconds)
+	sp.rpsMu.Unlock()
+	return float64(total) / float64(rpsWindowSeconds)
+}
+
+// SetGeographicSampleRate configures geo tracking to sample 1 in n requests.
+// Sampled requests are scaled back up by n so percentages stay representative.
+func (sp *ServerPool) SetGeographicSampleRate(n int) {
+	if n < 1 {
+		n = 1
 	}
-	
-	return 0.0
+	atomic.StoreUint64(&sp.geoSampleRate, uint64(n))
 }
 
 // TrackRequestWithIP tracks a request with geographic information
 func (sp *ServerPool) TrackRequestWithIP(req *http.Request) {
 	// Increment request count
 	sp.IncrementRequestCount()
-	
+
 	// Track geographic data if tracker is available
-	if sp.geoTracker != nil {
-		clientIP := geographic.ExtractClientIP(req)
-		sp.geoTracker.TrackRequest(clientIP)
+	if sp.geoTracker == nil {
+		return
+	}
+
+	rate := atomic.LoadUint64(&sp.geoSampleRate)
+	if rate <= 1 {
+		sp.geoTracker.TrackRequest(geographic.ExtractClientIP(req))
+		return
+	}
+
+	// Only every rate-th request is tracked; its count stands in for the rest
+	if atomic.AddUint64(&sp.geoSampleCounter, 1)%rate == 0 {
+		sp.geoTracker.TrackRequestN(geographic.ExtractClientIP(req), rate)
 	}
 }
 
@@ -179,6 +459,37 @@ func (sp *ServerPool) GetGeographicStats() []*geographic.CountryStats {
 	return sp.geoTracker.GetGeographicStats()
 }
 
+// LookupGeographicData resolves geographic data for a single IP via the
+// tracker, honoring its cache. Returns nil if tracking is disabled or the
+// lookup fails.
+func (sp *ServerPool) LookupGeographicData(ip string) *geographic.GeographicData {
+	if sp.geoTracker == nil {
+		return nil
+	}
+	return sp.geoTracker.GetGeographicData(ip)
+}
+
+// GetGeographicTracker returns the pool's geographic tracker, or nil if
+// geographic tracking is disabled.
+func (sp *ServerPool) GetGeographicTracker() *geographic.GeographicTracker {
+	return sp.geoTracker
+}
+
+// ResetGeographicStats clears accumulated geographic statistics
+func (sp *ServerPool) ResetGeographicStats() {
+	if sp.geoTracker != nil {
+		sp.geoTracker.ResetStats()
+	}
+}
+
+// GetGeographicCacheSize returns the number of cached geo lookups
+func (sp *ServerPool) GetGeographicCacheSize() int {
+	if sp.geoTracker == nil {
+		return 0
+	}
+	return sp.geoTracker.GetCacheSize()
+}
+
 // InitializeGeographicTracker initializes the geographic tracker with a logger
 func (sp *ServerPool) InitializeGeographicTracker(logger *zap.Logger) {
 	if sp.geoTracker == nil {
@@ -2,10 +2,16 @@ package loadbalancing
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"sync"
 	"testing"
 
+	"go.uber.org/zap"
+
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
+	"time"
 )
 
 type dummyAlgo struct{}
@@ -54,6 +60,51 @@ func TestGetHealthyServers(t *testing.T) {
 	}
 }
 
+func TestConcurrentSetAlgorithmAndSelect(t *testing.T) {
+	pool := NewServerPool(&dummyAlgo{})
+	pool.AddServer(newTestServer("s1", "http://localhost:9001", 1, model.HEALTHY))
+	pool.AddServer(newTestServer("s2", "http://localhost:9002", 2, model.HEALTHY))
+	req := httptest.NewRequest("GET", "/", nil)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// Swap the algorithm while selections are in flight; run with -race.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		algos := []LoadBalancingAlgorithm{
+			&RoundRobinAlgorithm{},
+			&LeastConnectionsAlgorithm{},
+			NewWeightedRoundRobinAlgorithm(),
+		}
+		for i := 0; i < 100; i++ {
+			pool.SetAlgorithm(algos[i%len(algos)])
+		}
+		close(done)
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					if pool.SelectBackend(req) == nil {
+						t.Error("expected a backend to be selected")
+						return
+					}
+					pool.Next()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestSetBackendStatus(t *testing.T) {
 	pool := NewServerPool(&dummyAlgo{})
 	s1 := newTestServer("s1", "http://localhost:9001", 1, model.UNHEALTHY)
@@ -64,3 +115,176 @@ func TestSetBackendStatus(t *testing.T) {
 		t.Errorf("expected s1 to be healthy after status update")
 	}
 }
+
+func newBenchPool(n int) *ServerPool {
+	pool := NewServerPool(&dummyAlgo{})
+	for i := 0; i < n; i++ {
+		pool.AddServer(newTestServer("s"+strconv.Itoa(i), "http://localhost:"+strconv.Itoa(9001+i), 1, model.HEALTHY))
+	}
+	return pool
+}
+
+func BenchmarkHealthyServersLockedFilter(b *testing.B) {
+	pool := newBenchPool(10)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.filterHealthyServers()
+		}
+	})
+}
+
+func BenchmarkHealthyServersSnapshot(b *testing.B) {
+	pool := newBenchPool(10)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pool.GetHealthyServers()
+		}
+	})
+}
+
+func TestGeographicTrackingSampling(t *testing.T) {
+	pool := NewServerPoolWithLogger(&dummyAlgo{}, zap.NewNop())
+	pool.SetGeographicSampleRate(4)
+
+	// Private IPs resolve to the "Local Network" stub without network calls
+	for i := 0; i < 100; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		pool.TrackRequestWithIP(req)
+	}
+
+	stats := pool.GetGeographicStats()
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 country entry, got %d", len(stats))
+	}
+	// 100 requests at 1-in-4 sampling = 25 samples scaled back up by 4
+	if stats[0].Requests != 100 {
+		t.Errorf("expected scaled count of 100 requests, got %d", stats[0].Requests)
+	}
+	if pool.GetTotalRequests() != 100 {
+		t.Errorf("expected 100 total requests, got %d", pool.GetTotalRequests())
+	}
+}
+
+func TestMaxBackendsLimit(t *testing.T) {
+	pool := NewServerPool(&RoundRobinAlgorithm{})
+	pool.SetMaxBackends(2)
+
+	for i := 0; i < 2; i++ {
+		server, err := model.NewBackendServer("s"+strconv.Itoa(i), "http://localhost:900"+strconv.Itoa(i), 1)
+		if err != nil {
+			t.Fatalf("failed to create backend server: %v", err)
+		}
+		if err := pool.TryAddServer(server); err != nil {
+			t.Fatalf("expected add %d to succeed within the limit: %v", i, err)
+		}
+	}
+
+	extra, err := model.NewBackendServer("s2", "http://localhost:9002", 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	if err := pool.TryAddServer(extra); err == nil {
+		t.Error("expected adding beyond maxBackends to fail")
+	}
+	if got := len(pool.GetServers()); got != 2 {
+		t.Errorf("expected pool to stay at 2 servers, got %d", got)
+	}
+
+	// Removing a server frees a slot
+	pool.RemoveServer("s0")
+	if err := pool.TryAddServer(extra); err != nil {
+		t.Errorf("expected add to succeed after removal: %v", err)
+	}
+}
+
+func TestRequestsPerSecondDecaysToZeroAfterBurst(t *testing.T) {
+	pool := NewServerPool(&RoundRobinAlgorithm{})
+	clock := time.Now()
+	pool.nowFunc = func() time.Time { return clock }
+
+	// A burst of 100 requests within one second
+	for i := 0; i < 100; i++ {
+		pool.IncrementRequestCount()
+	}
+	if rps := pool.GetRequestsPerSecond(); rps <= 0 {
+		t.Fatalf("expected a positive RPS right after the burst, got %v", rps)
+	}
+
+	// Halfway through the window the burst still counts
+	clock = clock.Add(rpsWindowSeconds / 2 * time.Second)
+	if rps := pool.GetRequestsPerSecond(); rps <= 0 {
+		t.Errorf("expected a positive RPS within the window, got %v", rps)
+	}
+
+	// Once the window has passed with no traffic, the rate is back to zero
+	clock = clock.Add(rpsWindowSeconds * time.Second)
+	if rps := pool.GetRequestsPerSecond(); rps != 0 {
+		t.Errorf("expected RPS 0 after traffic ceased, got %v", rps)
+	}
+}
+
+func TestRemoveServerPurgesWeightedState(t *testing.T) {
+	wrr := NewWeightedRoundRobinAlgorithm()
+	pool := NewServerPool(wrr)
+
+	a, _ := model.NewBackendServer("wrr-a", "http://localhost:9001", 2)
+	b, _ := model.NewBackendServer("wrr-b", "http://localhost:9002", 1)
+	c, _ := model.NewBackendServer("wrr-c", "http://localhost:9003", 1)
+	for _, srv := range []*model.BackendServer{a, b, c} {
+		srv.SetStatus(model.HEALTHY)
+		pool.AddServer(srv)
+	}
+
+	// Build up smooth-weight state across every server.
+	for i := 0; i < 8; i++ {
+		pool.SelectBackend(httptest.NewRequest("GET", "/", nil))
+	}
+
+	if !pool.RemoveServer("wrr-c") {
+		t.Fatal("expected wrr-c to be removed")
+	}
+	wrr.mu.RLock()
+	_, leaked := wrr.currentWeights["wrr-c"]
+	wrr.mu.RUnlock()
+	if leaked {
+		t.Error("expected weight entry for removed server to be purged")
+	}
+
+	// The remaining servers keep their 2:1 distribution.
+	counts := map[string]int{}
+	for i := 0; i < 30; i++ {
+		if srv := pool.SelectBackend(httptest.NewRequest("GET", "/", nil)); srv != nil {
+			counts[srv.ID]++
+		}
+	}
+	if counts["wrr-c"] != 0 {
+		t.Errorf("removed server still selected %d times", counts["wrr-c"])
+	}
+	if counts["wrr-a"] != 2*counts["wrr-b"] {
+		t.Errorf("expected a 2:1 split between wrr-a and wrr-b, got %v", counts)
+	}
+}
+
+func TestSelectionLatencyMetricPopulated(t *testing.T) {
+	pool := NewServerPool(&RoundRobinAlgorithm{})
+	server := &model.BackendServer{ID: "s1", HealthStatus: model.HEALTHY}
+	pool.AddServer(server)
+
+	if _, _, samples := pool.GetSelectionLatency(); samples != 0 {
+		t.Errorf("expected no samples before any selection, got %d", samples)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	for i := 0; i < 10; i++ {
+		pool.SelectBackend(req)
+	}
+
+	avg, p99, samples := pool.GetSelectionLatency()
+	if samples != 10 {
+		t.Errorf("expected 10 samples, got %d", samples)
+	}
+	if avg < 0 || p99 < 0 {
+		t.Errorf("expected non-negative latency stats, got avg=%f p99=%f", avg, p99)
+	}
+}
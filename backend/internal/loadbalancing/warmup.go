@@ -0,0 +1,54 @@
+package loadbalancing
+
+import (
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
+)
+
+// warmUpHoldDuration is how long warm-up connections are held open before
+// being released.
+const warmUpHoldDuration = 500 * time.Millisecond
+
+// warmUpDialTimeout bounds each warm-up dial so a dead backend doesn't stall
+// the caller.
+const warmUpDialTimeout = 2 * time.Second
+
+// WarmUpBackend opens count idle TCP connections to the backend and holds
+// them briefly, so the first real requests don't pay the full handshake cost.
+// No HTTP traffic is sent. Dial failures are logged and ignored.
+func WarmUpBackend(server *model.BackendServer, count int, log *zap.Logger) {
+	if count <= 0 {
+		return
+	}
+
+	conns := make([]net.Conn, 0, count)
+	for i := 0; i < count; i++ {
+		conn, err := net.DialTimeout("tcp", server.URL.Host, warmUpDialTimeout)
+		if err != nil {
+			log.Debug("Backend warm-up dial failed",
+				zap.String("server_id", server.ID),
+				zap.String("url", server.URL.String()),
+				zap.Error(err))
+			break
+		}
+		conns = append(conns, conn)
+	}
+	if len(conns) == 0 {
+		return
+	}
+
+	log.Debug("Warmed up backend connections",
+		zap.String("server_id", server.ID),
+		zap.Int("connections", len(conns)))
+
+	go func() {
+		time.Sleep(warmUpHoldDuration)
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+}
@@ -0,0 +1,57 @@
+package loadbalancing
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
+)
+
+func TestWarmUpBackendEstablishesConnections(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start listener: %v", err)
+	}
+	defer listener.Close()
+
+	var accepted int64
+	var bytesRead int64
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt64(&accepted, 1)
+			go func(c net.Conn) {
+				buf := make([]byte, 64)
+				n, _ := c.Read(buf)
+				atomic.AddInt64(&bytesRead, int64(n))
+				c.Close()
+			}(conn)
+		}
+	}()
+
+	server, err := model.NewBackendServer("warm", "http://"+listener.Addr().String(), 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+
+	WarmUpBackend(server, 3, zap.NewNop())
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt64(&accepted) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := atomic.LoadInt64(&accepted); got != 3 {
+		t.Errorf("expected 3 warm-up connections, got %d", got)
+	}
+	if got := atomic.LoadInt64(&bytesRead); got != 0 {
+		t.Errorf("expected no request bytes on warm-up connections, got %d", got)
+	}
+}
@@ -1,15 +1,95 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/httputils"
 )
 
-// APIKeyAuthMiddleware provides API key authentication for handlers.
+// APIKeyStore holds the accepted API keys and supports runtime rotation: for
+// a grace period after a rotation the previous key is still accepted, so
+// clients can switch over without a hard cutoff.
+type APIKeyStore struct {
+	mu             sync.RWMutex
+	current        string
+	previous       string
+	previousExpiry time.Time
+}
+
+// NewAPIKeyStore creates a store accepting only the given key.
+func NewAPIKeyStore(key string) *APIKeyStore {
+	return &APIKeyStore{current: key}
+}
+
+// Rotate installs a new key. The old key keeps working for the grace period;
+// a zero grace revokes it immediately.
+func (ks *APIKeyStore) Rotate(newKey string, grace time.Duration) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.previous = ks.current
+	ks.previousExpiry = time.Now().Add(grace)
+	ks.current = newKey
+}
+
+// Validate reports whether the key matches the current key, or the previous
+// one while still inside the rotation grace window.
+func (ks *APIKeyStore) Validate(key string) bool {
+	ks.mu.RLock()
+	current, previous, expiry := ks.current, ks.previous, ks.previousExpiry
+	ks.mu.RUnlock()
+	if subtle.ConstantTimeCompare([]byte(key), []byte(current)) == 1 {
+		return true
+	}
+	return previous != "" && time.Now().Before(expiry) &&
+		subtle.ConstantTimeCompare([]byte(key), []byte(previous)) == 1
+}
+
+// OptionalAPIKeyAuthMiddleware wraps next with API key authentication unless
+// enabled is false, for deployments where the API already sits behind a
+// trusted gateway. Callers disabling auth should log a prominent warning.
+// A non-empty keyHeader is accepted as an alternative to the Bearer scheme.
+func OptionalAPIKeyAuthMiddleware(next http.Handler, validAPIKey, keyHeader string, enabled bool) http.Handler {
+	if !enabled {
+		return next
+	}
+	return APIKeyAuthMiddlewareWithHeader(next, validAPIKey, keyHeader)
+}
+
+// OptionalAPIKeyAuthMiddlewareWithStore behaves like
+// OptionalAPIKeyAuthMiddleware but validates against a rotatable key store
+// instead of a fixed key.
+func OptionalAPIKeyAuthMiddlewareWithStore(next http.Handler, keys *APIKeyStore, keyHeader string, enabled bool) http.Handler {
+	if !enabled {
+		return next
+	}
+	return apiKeyAuthMiddleware(next, keys.Validate, keyHeader)
+}
+
+// APIKeyAuthMiddleware provides API key authentication for handlers using the
+// Authorization Bearer scheme.
 func APIKeyAuthMiddleware(next http.Handler, validAPIKey string) http.Handler {
+	return APIKeyAuthMiddlewareWithHeader(next, validAPIKey, "")
+}
+
+// APIKeyAuthMiddlewareWithHeader behaves like APIKeyAuthMiddleware but, when
+// keyHeader is non-empty, also accepts the API key in that header (e.g.
+// X-API-Key) for clients and proxies that can't set Authorization.
+func APIKeyAuthMiddlewareWithHeader(next http.Handler, validAPIKey, keyHeader string) http.Handler {
+	return apiKeyAuthMiddleware(next, func(key string) bool {
+		// Constant-time comparison so response timing leaks nothing about
+		// how much of the key matched.
+		return subtle.ConstantTimeCompare([]byte(key), []byte(validAPIKey)) == 1
+	}, keyHeader)
+}
+
+// apiKeyAuthMiddleware is the shared auth core: public endpoints and CORS
+// preflights pass through, everything else needs a key accepted by validate.
+func apiKeyAuthMiddleware(next http.Handler, validate func(string) bool, keyHeader string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Skip auth for OPTIONS requests (CORS preflight)
 		if r.Method == "OPTIONS" {
@@ -33,20 +113,31 @@ func APIKeyAuthMiddleware(next http.Handler, validAPIKey string) http.Handler {
 			}
 		}
 
+		if keyHeader != "" {
+			if key := r.Header.Get(keyHeader); key != "" {
+				if validate(key) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				httputils.RespondErrorCtx(r.Context(), w, http.StatusUnauthorized, fmt.Errorf("invalid API key"))
+				return
+			}
+		}
+
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			httputils.RespondError(w, http.StatusUnauthorized, fmt.Errorf("authorization header required"))
+			httputils.RespondErrorCtx(r.Context(), w, http.StatusUnauthorized, fmt.Errorf("authorization header required"))
 			return
 		}
 
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			httputils.RespondError(w, http.StatusUnauthorized, fmt.Errorf("invalid authorization header format"))
+			httputils.RespondErrorCtx(r.Context(), w, http.StatusUnauthorized, fmt.Errorf("invalid authorization header format"))
 			return
 		}
 
-		if parts[1] != validAPIKey {
-			httputils.RespondError(w, http.StatusUnauthorized, fmt.Errorf("invalid API key"))
+		if !validate(parts[1]) {
+			httputils.RespondErrorCtx(r.Context(), w, http.StatusUnauthorized, fmt.Errorf("invalid API key"))
 			return
 		}
 		next.ServeHTTP(w, r)
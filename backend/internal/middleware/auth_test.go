@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func dummyHandler(w http.ResponseWriter, r *http.Request) {
@@ -45,3 +46,122 @@ func TestAPIKeyAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestOptionalAPIKeyAuthMiddleware(t *testing.T) {
+	const validKey = "test-api-key"
+
+	// Enabled behaves exactly like APIKeyAuthMiddleware
+	enabled := OptionalAPIKeyAuthMiddleware(http.HandlerFunc(dummyHandler), validKey, "", true)
+	req := httptest.NewRequest("GET", "/api/v1/servers", nil)
+	rw := httptest.NewRecorder()
+	enabled.ServeHTTP(rw, req)
+	if rw.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without a key when auth is enabled, got %d", rw.Code)
+	}
+
+	// Disabled skips the key check entirely
+	disabled := OptionalAPIKeyAuthMiddleware(http.HandlerFunc(dummyHandler), validKey, "", false)
+	req = httptest.NewRequest("GET", "/api/v1/servers", nil)
+	rw = httptest.NewRecorder()
+	disabled.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK {
+		t.Errorf("expected status 200 without a key when auth is disabled, got %d", rw.Code)
+	}
+}
+
+func TestAPIKeyComparisonEdgeCases(t *testing.T) {
+	const validKey = "test-api-key"
+	middleware := APIKeyAuthMiddleware(http.HandlerFunc(dummyHandler), validKey)
+
+	tests := []struct {
+		name           string
+		key            string
+		expectedStatus int
+	}{
+		{"exact match", "test-api-key", http.StatusOK},
+		{"prefix of the valid key", "test-api", http.StatusUnauthorized},
+		{"valid key plus suffix", "test-api-key-extra", http.StatusUnauthorized},
+		{"empty key", "", http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.key)
+			rw := httptest.NewRecorder()
+			middleware.ServeHTTP(rw, req)
+			if rw.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d", tc.expectedStatus, rw.Code)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthMiddlewareWithCustomHeader(t *testing.T) {
+	const validKey = "test-api-key"
+	middleware := APIKeyAuthMiddlewareWithHeader(http.HandlerFunc(dummyHandler), validKey, "X-API-Key")
+
+	tests := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{"valid key via custom header", "X-API-Key", "test-api-key", http.StatusOK},
+		{"wrong key via custom header", "X-API-Key", "wrong-key", http.StatusUnauthorized},
+		{"bearer path still works", "Authorization", "Bearer test-api-key", http.StatusOK},
+		{"no credentials at all", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			rw := httptest.NewRecorder()
+			middleware.ServeHTTP(rw, req)
+			if rw.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d", tc.expectedStatus, rw.Code)
+			}
+		})
+	}
+}
+
+func TestAPIKeyStoreRotationGraceWindow(t *testing.T) {
+	store := NewAPIKeyStore("old-key-0123456789")
+	handler := OptionalAPIKeyAuthMiddlewareWithStore(http.HandlerFunc(dummyHandler), store, "", true)
+
+	request := func(key string) int {
+		req := httptest.NewRequest("GET", "/api/v1/servers", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := request("old-key-0123456789"); got != http.StatusOK {
+		t.Fatalf("expected the initial key to be accepted, got %d", got)
+	}
+
+	// During the grace window both old and new keys work
+	store.Rotate("new-key-0123456789", time.Minute)
+	if got := request("new-key-0123456789"); got != http.StatusOK {
+		t.Errorf("expected the new key to be accepted after rotation, got %d", got)
+	}
+	if got := request("old-key-0123456789"); got != http.StatusOK {
+		t.Errorf("expected the old key to be accepted inside the grace window, got %d", got)
+	}
+	if got := request("wrong-key"); got != http.StatusUnauthorized {
+		t.Errorf("expected an unknown key to be rejected, got %d", got)
+	}
+
+	// A zero grace revokes the previous key immediately
+	store.Rotate("final-key-0123456789", 0)
+	if got := request("new-key-0123456789"); got != http.StatusUnauthorized {
+		t.Errorf("expected the replaced key to be rejected with zero grace, got %d", got)
+	}
+	if got := request("final-key-0123456789"); got != http.StatusOK {
+		t.Errorf("expected the latest key to be accepted, got %d", got)
+	}
+}
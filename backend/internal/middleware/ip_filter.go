@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/geographic"
+)
+
+// IPFilterAction is what a matching rule does with a request.
+type IPFilterAction string
+
+const (
+	IPFilterAllow IPFilterAction = "allow"
+	IPFilterDeny  IPFilterAction = "deny"
+)
+
+// IPFilterRule matches client IPs against a CIDR (a bare IP is treated as a
+// /32 or /128) and applies its action. Rules are evaluated in list order.
+type IPFilterRule struct {
+	ID     string         `json:"id"`
+	CIDR   string         `json:"cidr"`
+	Action IPFilterAction `json:"action"`
+	ipNet  *net.IPNet
+}
+
+// IPFilter holds an ordered rule list with an explicit default policy.
+// Evaluation is top-to-bottom: the first rule matching the client IP wins,
+// and a request matching no rule falls through to the default policy.
+type IPFilter struct {
+	mu           sync.RWMutex
+	rules        []*IPFilterRule
+	defaultAllow bool
+	nextID       int
+}
+
+// NewIPFilter creates an IP filter with the given default policy.
+func NewIPFilter(defaultAllow bool) *IPFilter {
+	return &IPFilter{defaultAllow: defaultAllow}
+}
+
+// parseFilterCIDR accepts either a CIDR or a bare IP address.
+func parseFilterCIDR(cidr string) (*net.IPNet, error) {
+	if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+		return ipNet, nil
+	}
+	ip := net.ParseIP(cidr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid CIDR or IP address: %q", cidr)
+	}
+	bits := 32
+	if ip.To4() == nil {
+		bits = 128
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// AddRule appends a rule at the end of the evaluation order and returns it.
+func (f *IPFilter) AddRule(cidr string, action IPFilterAction) (*IPFilterRule, error) {
+	if action != IPFilterAllow && action != IPFilterDeny {
+		return nil, fmt.Errorf("invalid IP filter action: %q", action)
+	}
+	ipNet, err := parseFilterCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	rule := &IPFilterRule{
+		ID:     "ipf-" + strconv.Itoa(f.nextID),
+		CIDR:   cidr,
+		Action: action,
+		ipNet:  ipNet,
+	}
+	f.rules = append(f.rules, rule)
+	return rule, nil
+}
+
+// RemoveRule deletes a rule by ID, keeping the remaining order intact.
+func (f *IPFilter) RemoveRule(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, rule := range f.rules {
+		if rule.ID == id {
+			f.rules = append(f.rules[:i], f.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the rules in evaluation order.
+func (f *IPFilter) Rules() []*IPFilterRule {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	rules := make([]*IPFilterRule, len(f.rules))
+	copy(rules, f.rules)
+	return rules
+}
+
+// Reorder rearranges the rules to match ids, which must be a permutation of
+// the current rule IDs so no rule is silently dropped or duplicated.
+func (f *IPFilter) Reorder(ids []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(ids) != len(f.rules) {
+		return fmt.Errorf("reorder needs all %d rule IDs, got %d", len(f.rules), len(ids))
+	}
+	byID := make(map[string]*IPFilterRule, len(f.rules))
+	for _, rule := range f.rules {
+		byID[rule.ID] = rule
+	}
+	reordered := make([]*IPFilterRule, 0, len(ids))
+	for _, id := range ids {
+		rule, ok := byID[id]
+		if !ok {
+			return fmt.Errorf("unknown or duplicate rule ID in reorder: %q", id)
+		}
+		delete(byID, id)
+		reordered = append(reordered, rule)
+	}
+	f.rules = reordered
+	return nil
+}
+
+// SetDefaultPolicy switches between allow-by-default and deny-by-default.
+func (f *IPFilter) SetDefaultPolicy(allow bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.defaultAllow = allow
+}
+
+// DefaultAllow reports the current default policy.
+func (f *IPFilter) DefaultAllow() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.defaultAllow
+}
+
+// Allowed evaluates the rules top-to-bottom for the given client IP. An
+// unparseable IP falls through to the default policy.
+func (f *IPFilter) Allowed(clientIP string) bool {
+	ip := net.ParseIP(clientIP)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if ip != nil {
+		for _, rule := range f.rules {
+			if rule.ipNet.Contains(ip) {
+				return rule.Action == IPFilterAllow
+			}
+		}
+	}
+	return f.defaultAllow
+}
+
+// clientIPFromRequest resolves the trusted-proxy-aware client IP, stripping
+// the port that the RemoteAddr fallback carries.
+func clientIPFromRequest(r *http.Request) string {
+	clientIP := geographic.ExtractClientIP(r)
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		clientIP = host
+	}
+	return clientIP
+}
+
+// IPFilterMiddleware rejects requests from denied client IPs with 403.
+func IPFilterMiddleware(next http.Handler, filter *IPFilter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !filter.Allowed(clientIPFromRequest(r)) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPFilterDenyByDefaultWithAllowRules(t *testing.T) {
+	filter := NewIPFilter(false) // deny-by-default
+	if _, err := filter.AddRule("10.0.0.0/8", IPFilterAllow); err != nil {
+		t.Fatalf("failed to add rule: %v", err)
+	}
+	if _, err := filter.AddRule("192.168.1.50", IPFilterAllow); err != nil {
+		t.Fatalf("failed to add bare-IP rule: %v", err)
+	}
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"10.1.2.3", true},
+		{"192.168.1.50", true},
+		{"192.168.1.51", false},
+		{"203.0.113.9", false},
+	}
+	for _, tc := range tests {
+		if got := filter.Allowed(tc.ip); got != tc.allowed {
+			t.Errorf("Allowed(%q) = %v, want %v", tc.ip, got, tc.allowed)
+		}
+	}
+}
+
+func TestIPFilterAllowByDefaultWithDenyRules(t *testing.T) {
+	filter := NewIPFilter(true) // allow-by-default
+	if _, err := filter.AddRule("203.0.113.0/24", IPFilterDeny); err != nil {
+		t.Fatalf("failed to add rule: %v", err)
+	}
+
+	if filter.Allowed("203.0.113.9") {
+		t.Error("expected 203.0.113.9 to be denied")
+	}
+	if !filter.Allowed("198.51.100.7") {
+		t.Error("expected 198.51.100.7 to be allowed by default")
+	}
+}
+
+func TestIPFilterOrderingFirstMatchWins(t *testing.T) {
+	filter := NewIPFilter(false)
+	allowRule, _ := filter.AddRule("10.0.0.5", IPFilterAllow)
+	denyRule, _ := filter.AddRule("10.0.0.0/8", IPFilterDeny)
+
+	// The specific allow precedes the broad deny
+	if !filter.Allowed("10.0.0.5") {
+		t.Error("expected 10.0.0.5 allowed while the allow rule is first")
+	}
+	if filter.Allowed("10.0.0.6") {
+		t.Error("expected 10.0.0.6 denied by the broad rule")
+	}
+
+	// After reordering, the broad deny shadows the allow
+	if err := filter.Reorder([]string{denyRule.ID, allowRule.ID}); err != nil {
+		t.Fatalf("reorder failed: %v", err)
+	}
+	if filter.Allowed("10.0.0.5") {
+		t.Error("expected 10.0.0.5 denied once the deny rule is evaluated first")
+	}
+}
+
+func TestIPFilterReorderRejectsPartialLists(t *testing.T) {
+	filter := NewIPFilter(true)
+	rule, _ := filter.AddRule("10.0.0.0/8", IPFilterDeny)
+	filter.AddRule("192.168.0.0/16", IPFilterDeny)
+
+	if err := filter.Reorder([]string{rule.ID}); err == nil {
+		t.Error("expected an error for a reorder missing rule IDs")
+	}
+	if err := filter.Reorder([]string{rule.ID, "ipf-999"}); err == nil {
+		t.Error("expected an error for an unknown rule ID")
+	}
+}
+
+func TestIPFilterMiddleware(t *testing.T) {
+	filter := NewIPFilter(false)
+	filter.AddRule("10.0.0.0/8", IPFilterAllow)
+	handler := IPFilterMiddleware(http.HandlerFunc(dummyHandler), filter)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK {
+		t.Errorf("expected status 200 for an allowed IP, got %d", rw.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.9:4567"
+	rw = httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	if rw.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a denied IP, got %d", rw.Code)
+	}
+}
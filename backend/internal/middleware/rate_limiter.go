@@ -1,18 +1,41 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 
 	"golang.org/x/time/rate"
 )
 
-// RateLimitMiddleware applies a rate limiter to the handler.
+// setRateLimitHeaders exposes the token bucket state to clients so they can
+// pace themselves: the bucket size, roughly how many requests remain, and how
+// many seconds until the bucket refills completely.
+func setRateLimitHeaders(w http.ResponseWriter, limiter *rate.Limiter) {
+	tokens := limiter.Tokens()
+	remaining := int(tokens)
+	if remaining < 0 {
+		remaining = 0
+	}
+	resetSecs := 0
+	if limit := float64(limiter.Limit()); limit > 0 && tokens < float64(limiter.Burst()) {
+		resetSecs = int(math.Ceil((float64(limiter.Burst()) - tokens) / limit))
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limiter.Burst()))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(resetSecs))
+}
+
+// RateLimitMiddleware applies a rate limiter to the handler, advertising the
+// remaining quota on every response, not just rejected ones.
 func RateLimitMiddleware(next http.Handler, limiter *rate.Limiter) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !limiter.Allow() {
+			setRateLimitHeaders(w, limiter)
 			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
 			return
 		}
+		setRateLimitHeaders(w, limiter)
 		next.ServeHTTP(w, r)
 	})
 }
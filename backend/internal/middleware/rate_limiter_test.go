@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"golang.org/x/time/rate"
@@ -30,3 +31,41 @@ func TestRateLimitMiddleware(t *testing.T) {
 		t.Errorf("expected 429 Too Many Requests, got %d", rw.Code)
 	}
 }
+
+func TestRateLimitHeadersOnAllowedResponses(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Limit(0.001), 3) // Effectively no refill during the test
+	middleware := RateLimitMiddleware(http.HandlerFunc(dummyHandler), limiter)
+
+	var lastRemaining int
+	for i := 0; i < 3; i++ {
+		rw := httptest.NewRecorder()
+		middleware.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+		if rw.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200 OK, got %d", i+1, rw.Code)
+		}
+		if rw.Header().Get("X-RateLimit-Limit") != "3" {
+			t.Errorf("request %d: expected X-RateLimit-Limit 3, got %q", i+1, rw.Header().Get("X-RateLimit-Limit"))
+		}
+		remaining, err := strconv.Atoi(rw.Header().Get("X-RateLimit-Remaining"))
+		if err != nil {
+			t.Fatalf("request %d: missing or invalid X-RateLimit-Remaining: %v", i+1, err)
+		}
+		if i > 0 && remaining >= lastRemaining {
+			t.Errorf("request %d: expected remaining to decrement, got %d after %d", i+1, remaining, lastRemaining)
+		}
+		if rw.Header().Get("X-RateLimit-Reset") == "" {
+			t.Errorf("request %d: missing X-RateLimit-Reset header", i+1)
+		}
+		lastRemaining = remaining
+	}
+
+	// The rejection also carries the quota headers
+	rw := httptest.NewRecorder()
+	middleware.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	if rw.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rw.Code)
+	}
+	if rw.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("expected X-RateLimit-Remaining 0 on 429, got %q", rw.Header().Get("X-RateLimit-Remaining"))
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/httputils"
+)
+
+// RequestIDHeader carries the request ID between clients, the balancer, and
+// backends.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDMiddleware assigns every request an ID: an inbound X-Request-ID is
+// kept so IDs stay stable across hops, otherwise a random one is generated.
+// The ID is echoed on the response header and stored on the context so
+// response envelopes can include it.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(httputils.WithRequestID(r.Context(), id)))
+	})
+}
+
+// newRequestID generates a random 16-hex-character ID.
+func newRequestID() string {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf[:])
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/httputils"
+)
+
+func TestRequestIDMiddlewareGeneratesAndPropagates(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = httputils.RequestIDFromContext(r.Context())
+	}))
+
+	// Without an inbound ID, one is generated and echoed on the response.
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if seen == "" {
+		t.Error("expected a generated request ID on the context")
+	}
+	if got := w.Header().Get(RequestIDHeader); got != seen {
+		t.Errorf("expected response header %q to match context ID %q", got, seen)
+	}
+
+	// An inbound ID is kept so the ID stays stable across hops.
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "upstream-42")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if seen != "upstream-42" {
+		t.Errorf("expected inbound request ID to be kept, got %q", seen)
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// TimeoutMiddleware bounds handler execution to the given duration, returning
+// a JSON 503 response if the handler does not complete in time.
+func TimeoutMiddleware(next http.Handler, timeout time.Duration) http.Handler {
+	body, _ := json.Marshal(map[string]interface{}{
+		"error":      "request timed out",
+		"message":    "The server did not complete the request in time",
+		"statusCode": http.StatusServiceUnavailable,
+	})
+	return http.TimeoutHandler(next, timeout, string(body))
+}
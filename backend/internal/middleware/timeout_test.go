@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMiddlewareAllowsFastHandler(t *testing.T) {
+	middleware := TimeoutMiddleware(http.HandlerFunc(dummyHandler), 100*time.Millisecond)
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	middleware.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK {
+		t.Errorf("expected 200 OK, got %d", rw.Code)
+	}
+}
+
+func TestTimeoutMiddlewareFiresOnSlowHandler(t *testing.T) {
+	slowHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	middleware := TimeoutMiddleware(slowHandler, 50*time.Millisecond)
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	middleware.ServeHTTP(rw, req)
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 Service Unavailable, got %d", rw.Code)
+	}
+	if !strings.Contains(rw.Body.String(), "request timed out") {
+		t.Errorf("expected JSON timeout body, got %q", rw.Body.String())
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WAFRule blocks requests whose named field matches the pattern. An optional
+// SourceCIDR scopes the rule: it only fires when the client IP is inside the
+// range, so a pattern can be blocked e.g. only from outside the office.
+type WAFRule struct {
+	ID         string `json:"id"`
+	Field      string `json:"field"`                // path, query, user-agent, or header:<Name>
+	Pattern    string `json:"pattern"`              // Regular expression matched against the field
+	SourceCIDR string `json:"sourceCidr,omitempty"` // Optional client IP scope
+	re         *regexp.Regexp
+	ipNet      *net.IPNet
+}
+
+// WAF evaluates a set of blocking rules against incoming requests.
+type WAF struct {
+	mu     sync.RWMutex
+	rules  []*WAFRule
+	nextID int
+}
+
+// NewWAF creates an empty WAF.
+func NewWAF() *WAF {
+	return &WAF{}
+}
+
+// AddRule registers a blocking rule. An empty sourceCIDR applies the rule to
+// every client; otherwise it must parse as a CIDR or bare IP.
+func (w *WAF) AddRule(field, pattern, sourceCIDR string) (*WAFRule, error) {
+	switch {
+	case field == "path" || field == "query" || field == "user-agent":
+	case strings.HasPrefix(field, "header:") && len(field) > len("header:"):
+	default:
+		return nil, fmt.Errorf("invalid WAF field: %q (want path, query, user-agent, or header:<Name>)", field)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WAF pattern: %w", err)
+	}
+	var ipNet *net.IPNet
+	if sourceCIDR != "" {
+		ipNet, err = parseFilterCIDR(sourceCIDR)
+		if err != nil {
+			return nil, err
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.nextID++
+	rule := &WAFRule{
+		ID:         "waf-" + strconv.Itoa(w.nextID),
+		Field:      field,
+		Pattern:    pattern,
+		SourceCIDR: sourceCIDR,
+		re:         re,
+		ipNet:      ipNet,
+	}
+	w.rules = append(w.rules, rule)
+	return rule, nil
+}
+
+// RemoveRule deletes a rule by ID.
+func (w *WAF) RemoveRule(id string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, rule := range w.rules {
+		if rule.ID == id {
+			w.rules = append(w.rules[:i], w.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the registered rules.
+func (w *WAF) Rules() []*WAFRule {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	rules := make([]*WAFRule, len(w.rules))
+	copy(rules, w.rules)
+	return rules
+}
+
+// fieldValue extracts the request value a rule's field refers to.
+func fieldValue(r *http.Request, field string) string {
+	switch {
+	case field == "path":
+		return r.URL.Path
+	case field == "query":
+		return r.URL.RawQuery
+	case field == "user-agent":
+		return r.UserAgent()
+	case strings.HasPrefix(field, "header:"):
+		return r.Header.Get(strings.TrimPrefix(field, "header:"))
+	}
+	return ""
+}
+
+// Match returns the first rule blocking the request, or nil. A rule fires
+// only when both its pattern matches and, if set, the client IP falls inside
+// its SourceCIDR.
+func (w *WAF) Match(r *http.Request) *WAFRule {
+	var clientIP net.IP
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, rule := range w.rules {
+		if !rule.re.MatchString(fieldValue(r, rule.Field)) {
+			continue
+		}
+		if rule.ipNet != nil {
+			if clientIP == nil {
+				clientIP = net.ParseIP(clientIPFromRequest(r))
+			}
+			if clientIP == nil || !rule.ipNet.Contains(clientIP) {
+				continue
+			}
+		}
+		return rule
+	}
+	return nil
+}
+
+// WAFMiddleware rejects requests matching a WAF rule with 403.
+func WAFMiddleware(next http.Handler, waf *WAF) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rule := waf.Match(r); rule != nil {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWAFPatternMatching(t *testing.T) {
+	waf := NewWAF()
+	if _, err := waf.AddRule("path", `\.\./`, ""); err != nil {
+		t.Fatalf("failed to add rule: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/files/../etc/passwd", nil)
+	if waf.Match(req) == nil {
+		t.Error("expected the traversal pattern to match")
+	}
+	req = httptest.NewRequest("GET", "/files/report.pdf", nil)
+	if waf.Match(req) != nil {
+		t.Error("expected a clean path not to match")
+	}
+}
+
+func TestWAFSourceCIDRScopesRule(t *testing.T) {
+	waf := NewWAF()
+	// Block the admin path only for clients outside 10.0.0.0/8 — expressed
+	// here as "block when inside the suspicious range" for the test.
+	if _, err := waf.AddRule("path", "^/admin", "203.0.113.0/24"); err != nil {
+		t.Fatalf("failed to add rule: %v", err)
+	}
+
+	// Pattern and CIDR both match: blocked
+	req := httptest.NewRequest("GET", "/admin/users", nil)
+	req.RemoteAddr = "203.0.113.9:1234"
+	if waf.Match(req) == nil {
+		t.Error("expected a match when both pattern and source CIDR match")
+	}
+
+	// Pattern matches but the client is outside the CIDR: allowed
+	req = httptest.NewRequest("GET", "/admin/users", nil)
+	req.RemoteAddr = "198.51.100.7:1234"
+	if waf.Match(req) != nil {
+		t.Error("expected no match for a client outside the source CIDR")
+	}
+
+	// Client inside the CIDR but the pattern doesn't match: allowed
+	req = httptest.NewRequest("GET", "/public", nil)
+	req.RemoteAddr = "203.0.113.9:1234"
+	if waf.Match(req) != nil {
+		t.Error("expected no match when the pattern doesn't match")
+	}
+}
+
+func TestWAFMiddlewareBlocksWith403(t *testing.T) {
+	waf := NewWAF()
+	if _, err := waf.AddRule("user-agent", "(?i)sqlmap", ""); err != nil {
+		t.Fatalf("failed to add rule: %v", err)
+	}
+	handler := WAFMiddleware(http.HandlerFunc(dummyHandler), waf)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", "sqlmap/1.0")
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	if rw.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a blocked user agent, got %d", rw.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	rw = httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK {
+		t.Errorf("expected status 200 for a clean request, got %d", rw.Code)
+	}
+}
+
+func TestWAFAddRuleValidation(t *testing.T) {
+	waf := NewWAF()
+	if _, err := waf.AddRule("cookie", "x", ""); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if _, err := waf.AddRule("path", "([", ""); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+	if _, err := waf.AddRule("path", "x", "not-an-ip"); err == nil {
+		t.Error("expected an error for an invalid source CIDR")
+	}
+}
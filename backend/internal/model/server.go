@@ -1,7 +1,9 @@
 package model
 
 import (
+	"fmt"
 	"net/url"
+	"regexp"
 	"sync"
 	"time"
 )
@@ -16,24 +18,92 @@ const (
 	MAINTENANCE HealthStatus = "MAINTENANCE"
 )
 
+// maxServerIDLength bounds server IDs so they stay usable as cookie values
+// and log fields.
+const maxServerIDLength = 64
+
+// serverIDPattern restricts server IDs to a charset that is safe in cookies,
+// log fields, and the weighted-round-robin weight map.
+var serverIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateServerID checks that a server ID is non-empty, within the length
+// bound, and contains only alphanumerics, dashes, and underscores.
+func ValidateServerID(id string) error {
+	if id == "" {
+		return fmt.Errorf("server ID must not be empty")
+	}
+	if len(id) > maxServerIDLength {
+		return fmt.Errorf("server ID must be at most %d characters, got %d", maxServerIDLength, len(id))
+	}
+	if !serverIDPattern.MatchString(id) {
+		return fmt.Errorf("server ID %q contains invalid characters; only alphanumerics, dashes, and underscores are allowed", id)
+	}
+	return nil
+}
+
+// ValidateProxyProtocolVersion checks a per-backend PROXY protocol setting:
+// empty disables it, "v1" and "v2" select the header version sent upstream.
+func ValidateProxyProtocolVersion(version string) error {
+	switch version {
+	case "", "v1", "v2":
+		return nil
+	}
+	return fmt.Errorf("invalid proxy protocol version %q; expected \"v1\" or \"v2\"", version)
+}
+
+// HealthTransition records one health status change for a backend, kept for
+// post-incident analysis.
+type HealthTransition struct {
+	From      HealthStatus `json:"from"`
+	To        HealthStatus `json:"to"`
+	Timestamp time.Time    `json:"timestamp"`
+	Reason    string       `json:"reason,omitempty"`
+}
+
+// maxHealthHistory bounds how many transitions are kept per backend; the
+// oldest entries are dropped first.
+const maxHealthHistory = 50
+
 // BackendServer represents a single backend server that the load balancer can forward requests to.
 type BackendServer struct {
-	ID                string        `json:"id"`
-	URL               *url.URL      `json:"url"`
-	Weight            int           `json:"weight"`
-	ActiveConnections int64         `json:"activeConnections"` // Using int64 for atomic operations
-	HealthStatus      HealthStatus  `json:"healthStatus"`
-	lastHealthCheck   time.Time
-	lastResponseTime  time.Duration // Track response time from health checks
-	mu                sync.RWMutex  // Protects healthStatus, activeConnections, and responseTime
+	ID                 string            `json:"id"`
+	URL                *url.URL          `json:"url"`
+	Weight             int               `json:"weight"`
+	Tags               map[string]string `json:"tags,omitempty"`           // Free-form labels for grouping (e.g. region, canary)
+	Zone               string            `json:"zone,omitempty"`           // Availability zone, for same-zone routing preference
+	HealthCheckURL     *url.URL          `json:"healthCheckUrl,omitempty"` // Optional separate endpoint for health probes
+	ProxyProtocol      string            `json:"proxyProtocol,omitempty"`  // "v1" or "v2" to send a PROXY header upstream; empty sends none
+	ActiveConnections  int64             `json:"activeConnections"`        // Using int64 for atomic operations
+	HealthStatus       HealthStatus      `json:"healthStatus"`
+	lastHealthCheck    time.Time
+	lastResponseTime   time.Duration      // Most recent response-time sample
+	ewmaResponseTime   time.Duration      // Smoothed response time; see responseTimeEWMAAlpha
+	consecutiveFails   int                // Consecutive failed health checks
+	consecutiveOKs     int                // Consecutive successful health checks
+	peakConnections    int64              // Highest observed concurrent connection count
+	unhealthySince     time.Time          // When the server last entered UNHEALTHY
+	healthHistory      []HealthTransition // Recent status transitions, oldest first
+	checksRun          int64              // Total health checks run against this backend
+	checkFailures      int64              // Health checks that failed
+	totalCheckDuration time.Duration      // Sum of check durations, for the average
+	lastCheckDuration  time.Duration      // Most recent check duration
+	totalRequests      int64              // Requests routed to this backend
+	mu                 sync.RWMutex       // Protects healthStatus, activeConnections, and responseTime
 }
 
-// NewBackendServer creates a new BackendServer instance.
+// NewBackendServer creates a new BackendServer instance. A weight of zero or
+// less defaults to 1; weight 0 is reserved for draining an existing server.
 func NewBackendServer(id string, rawURL string, weight int) (*BackendServer, error) {
+	if err := ValidateServerID(id); err != nil {
+		return nil, err
+	}
 	u, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, err
 	}
+	if weight <= 0 {
+		weight = 1
+	}
 	return &BackendServer{
 		ID:                id,
 		URL:               u,
@@ -43,14 +113,134 @@ func NewBackendServer(id string, rawURL string, weight int) (*BackendServer, err
 	}, nil
 }
 
-// SetStatus atomically updates the health status.
+// SetHealthCheckEndpoint overrides where health checks probe this server.
+// A non-empty rawURL takes precedence; otherwise a non-zero port replaces the
+// service URL's port. With neither set, probes keep using the service URL.
+func (b *BackendServer) SetHealthCheckEndpoint(rawURL string, port int) error {
+	if rawURL != "" {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		b.HealthCheckURL = u
+		return nil
+	}
+	if port > 0 {
+		u := *b.URL
+		u.Host = fmt.Sprintf("%s:%d", b.URL.Hostname(), port)
+		b.HealthCheckURL = &u
+	}
+	return nil
+}
+
+// HealthURL returns the endpoint health checks should probe: the dedicated
+// health check URL when configured, the service URL otherwise.
+func (b *BackendServer) HealthURL() *url.URL {
+	if b.HealthCheckURL != nil {
+		return b.HealthCheckURL
+	}
+	return b.URL
+}
+
+// MatchesTag reports whether the server carries the given tag. An empty value
+// matches any value for the key.
+func (b *BackendServer) MatchesTag(key, value string) bool {
+	got, ok := b.Tags[key]
+	if !ok {
+		return false
+	}
+	return value == "" || got == value
+}
+
+// SetStatus atomically updates the health status, recording when the server
+// entered UNHEALTHY for cool-down tracking.
 func (b *BackendServer) SetStatus(status HealthStatus) {
+	b.SetStatusWithReason(status, "")
+}
+
+// SetStatusWithReason is SetStatus with a human-readable cause recorded in
+// the transition history (e.g. the failing check's error).
+func (b *BackendServer) SetStatusWithReason(status HealthStatus, reason string) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	if status == UNHEALTHY && b.HealthStatus != UNHEALTHY {
+		b.unhealthySince = time.Now()
+	}
+	if status != b.HealthStatus {
+		b.healthHistory = append(b.healthHistory, HealthTransition{
+			From:      b.HealthStatus,
+			To:        status,
+			Timestamp: time.Now(),
+			Reason:    reason,
+		})
+		if len(b.healthHistory) > maxHealthHistory {
+			b.healthHistory = b.healthHistory[len(b.healthHistory)-maxHealthHistory:]
+		}
+	}
 	b.HealthStatus = status
 	b.lastHealthCheck = time.Now()
 }
 
+// HealthCheckStats summarizes the health-checking activity for one backend.
+type HealthCheckStats struct {
+	ChecksRun      int64   `json:"checksRun"`
+	Failures       int64   `json:"failures"`
+	LastDurationMs int64   `json:"lastDurationMs"`
+	AvgDurationMs  float64 `json:"avgDurationMs"`
+}
+
+// RecordCheckDuration notes one completed health check, its duration, and
+// whether it failed, feeding the health check metrics.
+func (b *BackendServer) RecordCheckDuration(d time.Duration, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checksRun++
+	if failed {
+		b.checkFailures++
+	}
+	b.totalCheckDuration += d
+	b.lastCheckDuration = d
+}
+
+// GetHealthCheckStats returns the accumulated health check counters.
+func (b *BackendServer) GetHealthCheckStats() HealthCheckStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	stats := HealthCheckStats{
+		ChecksRun:      b.checksRun,
+		Failures:       b.checkFailures,
+		LastDurationMs: b.lastCheckDuration.Milliseconds(),
+	}
+	if b.checksRun > 0 {
+		stats.AvgDurationMs = float64(b.totalCheckDuration.Milliseconds()) / float64(b.checksRun)
+	}
+	return stats
+}
+
+// GetHealthHistory returns the recorded status transitions, oldest first.
+func (b *BackendServer) GetHealthHistory() []HealthTransition {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	history := make([]HealthTransition, len(b.healthHistory))
+	copy(history, b.healthHistory)
+	return history
+}
+
+// UnhealthySince returns when the server last entered UNHEALTHY; the zero
+// time if it never has.
+func (b *BackendServer) UnhealthySince() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.unhealthySince
+}
+
+// IsDraining reports whether the server is drained out of new-request
+// rotation: weight zero keeps it in the pool for sticky sessions and
+// in-flight requests while selection skips it.
+func (b *BackendServer) IsDraining() bool {
+	return b.Weight <= 0
+}
+
 // IsAlive checks if the server is healthy or degraded.
 func (b *BackendServer) IsAlive() bool {
 	b.mu.RLock()
@@ -58,11 +248,16 @@ func (b *BackendServer) IsAlive() bool {
 	return b.HealthStatus == HEALTHY || b.HealthStatus == DEGRADED
 }
 
-// IncrementConnections atomically increments active connections.
+// IncrementConnections atomically increments active connections, tracking the
+// peak concurrency and the total requests routed to this backend.
 func (b *BackendServer) IncrementConnections() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.ActiveConnections++
+	b.totalRequests++
+	if b.ActiveConnections > b.peakConnections {
+		b.peakConnections = b.ActiveConnections
+	}
 }
 
 // DecrementConnections atomically decrements active connections.
@@ -81,11 +276,56 @@ func (b *BackendServer) GetActiveConnections() int64 {
 	return b.ActiveConnections
 }
 
-// SetResponseTime atomically updates the last response time.
+// RecordCheckFailure notes a failed health check, resetting the success
+// streak, and returns the current consecutive-failure count.
+func (b *BackendServer) RecordCheckFailure() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveOKs = 0
+	b.consecutiveFails++
+	return b.consecutiveFails
+}
+
+// RecordCheckSuccess notes a successful health check, resetting the failure
+// streak, and returns the current consecutive-success count.
+func (b *BackendServer) RecordCheckSuccess() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFails = 0
+	b.consecutiveOKs++
+	return b.consecutiveOKs
+}
+
+// GetPeakConnections returns the highest concurrent connection count observed.
+func (b *BackendServer) GetPeakConnections() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.peakConnections
+}
+
+// GetTotalRequests returns how many requests have been routed to this backend.
+func (b *BackendServer) GetTotalRequests() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.totalRequests
+}
+
+// responseTimeEWMAAlpha weights new samples in the response-time moving
+// average; higher values react faster, lower values smooth more.
+const responseTimeEWMAAlpha = 0.3
+
+// SetResponseTime records a response-time sample, updating both the latest
+// value and the exponentially-weighted moving average.
 func (b *BackendServer) SetResponseTime(responseTime time.Duration) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.lastResponseTime = responseTime
+	if b.ewmaResponseTime == 0 {
+		b.ewmaResponseTime = responseTime
+	} else {
+		b.ewmaResponseTime = time.Duration(
+			responseTimeEWMAAlpha*float64(responseTime) + (1-responseTimeEWMAAlpha)*float64(b.ewmaResponseTime))
+	}
 }
 
 // GetResponseTime returns the last recorded response time in milliseconds.
@@ -94,3 +334,25 @@ func (b *BackendServer) GetResponseTime() int64 {
 	defer b.mu.RUnlock()
 	return b.lastResponseTime.Nanoseconds() / int64(time.Millisecond)
 }
+
+// GetResponseTimeEWMA returns the smoothed response time in milliseconds.
+func (b *BackendServer) GetResponseTimeEWMA() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.ewmaResponseTime.Nanoseconds() / int64(time.Millisecond)
+}
+
+// GetResponseTimeMicros returns the last recorded response time in
+// microseconds, so sub-millisecond backends don't all flatten to zero.
+func (b *BackendServer) GetResponseTimeMicros() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.lastResponseTime.Microseconds()
+}
+
+// GetResponseTimeEWMAMicros returns the smoothed response time in microseconds.
+func (b *BackendServer) GetResponseTimeEWMAMicros() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.ewmaResponseTime.Microseconds()
+}
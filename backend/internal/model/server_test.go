@@ -0,0 +1,108 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateServerID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		wantErr bool
+	}{
+		{"simple alphanumeric", "server1", false},
+		{"dashes and underscores", "web_backend-01", false},
+		{"max length", strings.Repeat("a", 64), false},
+		{"empty", "", true},
+		{"newline", "server\n1", true},
+		{"space", "server 1", true},
+		{"control character", "server\x001", true},
+		{"overly long", strings.Repeat("a", 65), true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateServerID(tt.id)
+		if tt.wantErr && err == nil {
+			t.Errorf("%s: expected an error for ID %q", tt.name, tt.id)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: unexpected error for ID %q: %v", tt.name, tt.id, err)
+		}
+	}
+}
+
+func TestNewBackendServerRejectsInvalidID(t *testing.T) {
+	if _, err := NewBackendServer("bad\nid", "http://localhost:9001", 1); err == nil {
+		t.Error("expected an error for an ID containing a newline")
+	}
+	if _, err := NewBackendServer("good-id", "http://localhost:9001", 1); err != nil {
+		t.Errorf("unexpected error for a valid ID: %v", err)
+	}
+}
+
+func TestResponseTimeEWMASmoothsSamples(t *testing.T) {
+	server, err := NewBackendServer("ewma", "http://localhost:9001", 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+
+	// A steady stream settles the EWMA on the steady value
+	for i := 0; i < 5; i++ {
+		server.SetResponseTime(100 * time.Millisecond)
+	}
+	if got := server.GetResponseTimeEWMA(); got != 100 {
+		t.Errorf("expected EWMA 100ms after steady samples, got %dms", got)
+	}
+
+	// A single spike moves the EWMA only partway toward the outlier
+	server.SetResponseTime(1000 * time.Millisecond)
+	if got := server.GetResponseTime(); got != 1000 {
+		t.Errorf("expected latest sample 1000ms, got %dms", got)
+	}
+	ewma := server.GetResponseTimeEWMA()
+	if ewma <= 100 || ewma >= 1000 {
+		t.Errorf("expected EWMA between 100ms and 1000ms after a spike, got %dms", ewma)
+	}
+	if ewma > 500 {
+		t.Errorf("expected EWMA to stay closer to the steady value than the spike, got %dms", ewma)
+	}
+}
+
+func TestPeakConnectionsTracksMaximum(t *testing.T) {
+	server, err := NewBackendServer("peak", "http://localhost:9001", 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+
+	// Ramp up to 3, drop to 1, climb back to 2: the peak stays at 3
+	server.IncrementConnections()
+	server.IncrementConnections()
+	server.IncrementConnections()
+	server.DecrementConnections()
+	server.DecrementConnections()
+	server.IncrementConnections()
+
+	if got := server.GetActiveConnections(); got != 2 {
+		t.Errorf("expected 2 active connections, got %d", got)
+	}
+	if got := server.GetPeakConnections(); got != 3 {
+		t.Errorf("expected peak of 3 connections, got %d", got)
+	}
+}
+
+func TestSubMillisecondResponseTimeNotReportedAsZero(t *testing.T) {
+	server := &BackendServer{ID: "s1"}
+	server.SetResponseTime(450 * time.Microsecond)
+
+	if got := server.GetResponseTime(); got != 0 {
+		t.Errorf("expected millisecond getter to truncate to 0, got %d", got)
+	}
+	if got := server.GetResponseTimeMicros(); got != 450 {
+		t.Errorf("expected 450 microseconds, got %d", got)
+	}
+	if got := server.GetResponseTimeEWMAMicros(); got != 450 {
+		t.Errorf("expected EWMA seeded to 450 microseconds, got %d", got)
+	}
+}
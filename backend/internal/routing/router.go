@@ -3,6 +3,7 @@ package routing
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
 )
@@ -13,11 +14,16 @@ type RoutingRule struct {
 	PathPrefix   string
 	Method       string
 	TargetPoolID string
+	Timeout      time.Duration // Per-route response timeout; 0 uses the global timeout
 }
 
 type L7Router struct {
 	Pools map[string]*loadbalancing.ServerPool
 	Rules []RoutingRule
+
+	// DefaultPoolID names the pool served when no rule matches. Empty means
+	// no default: the caller decides whether to fall back or reject.
+	DefaultPoolID string
 }
 
 func NewL7Router() *L7Router {
@@ -29,7 +35,14 @@ func NewL7Router() *L7Router {
 
 // Route returns the ServerPool for the first matching rule, or nil if none match.
 func (r *L7Router) Route(req *http.Request) *loadbalancing.ServerPool {
-	for _, rule := range r.Rules {
+	pool, _ := r.RouteRule(req)
+	return pool
+}
+
+// RouteRule returns the ServerPool and the rule that matched the request, or
+// nil for both if no rule matches.
+func (r *L7Router) RouteRule(req *http.Request) (*loadbalancing.ServerPool, *RoutingRule) {
+	for i, rule := range r.Rules {
 		if rule.Host != "" && !strings.EqualFold(req.Host, rule.Host) {
 			continue
 		}
@@ -41,8 +54,13 @@ func (r *L7Router) Route(req *http.Request) *loadbalancing.ServerPool {
 		}
 		pool, ok := r.Pools[rule.TargetPoolID]
 		if ok {
-			return pool
+			return pool, &r.Rules[i]
+		}
+	}
+	if r.DefaultPoolID != "" {
+		if pool, ok := r.Pools[r.DefaultPoolID]; ok {
+			return pool, nil // Default pool, no rule matched
 		}
 	}
-	return nil
+	return nil, nil
 }
@@ -48,3 +48,44 @@ func TestL7Router_Route(t *testing.T) {
 		})
 	}
 }
+
+func TestL7Router_DefaultPoolWhenNoRuleMatches(t *testing.T) {
+	poolA := &loadbalancing.ServerPool{}
+	fallback := &loadbalancing.ServerPool{}
+	router := &L7Router{
+		Pools: map[string]*loadbalancing.ServerPool{
+			"A":       poolA,
+			"default": fallback,
+		},
+		Rules:         []RoutingRule{{ID: "1", PathPrefix: "/api", TargetPoolID: "A"}},
+		DefaultPoolID: "default",
+	}
+
+	// A matching rule still wins over the default
+	req, _ := http.NewRequest("GET", "http://example.com/api/users", nil)
+	pool, rule := router.RouteRule(req)
+	if pool != poolA || rule == nil || rule.ID != "1" {
+		t.Errorf("expected rule 1 to route to pool A, got pool=%v rule=%v", pool, rule)
+	}
+
+	// No rule matches: the default pool is returned with no rule
+	req, _ = http.NewRequest("GET", "http://example.com/static/app.js", nil)
+	pool, rule = router.RouteRule(req)
+	if pool != fallback {
+		t.Errorf("expected the default pool for an unrouted request, got %v", pool)
+	}
+	if rule != nil {
+		t.Errorf("expected no rule for a default-pool route, got %v", rule)
+	}
+}
+
+func TestL7Router_NoDefaultReturnsNil(t *testing.T) {
+	router := &L7Router{
+		Pools: map[string]*loadbalancing.ServerPool{"A": {}},
+		Rules: []RoutingRule{{ID: "1", PathPrefix: "/api", TargetPoolID: "A"}},
+	}
+	req, _ := http.NewRequest("GET", "http://example.com/static/app.js", nil)
+	if pool, rule := router.RouteRule(req); pool != nil || rule != nil {
+		t.Errorf("expected nil pool and rule with no default configured, got %v, %v", pool, rule)
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ConnMetrics tracks the TCP connection lifecycle of an http.Server through
+// its ConnState hook: how many connections were accepted and closed in total,
+// and how many are currently serving a request or sitting idle on keep-alive.
+type ConnMetrics struct {
+	mu     sync.Mutex
+	states map[net.Conn]http.ConnState
+	opened int64 // Total connections accepted
+	closed int64 // Total connections closed or hijacked
+}
+
+// NewConnMetrics creates an empty connection metrics tracker.
+func NewConnMetrics() *ConnMetrics {
+	return &ConnMetrics{states: make(map[net.Conn]http.ConnState)}
+}
+
+// ConnState is the http.Server ConnState callback feeding the counters.
+func (m *ConnMetrics) ConnState(conn net.Conn, state http.ConnState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch state {
+	case http.StateNew:
+		m.opened++
+		m.states[conn] = state
+	case http.StateActive, http.StateIdle:
+		m.states[conn] = state
+	case http.StateHijacked, http.StateClosed:
+		m.closed++
+		delete(m.states, conn)
+	}
+}
+
+// Counts returns the current counters: "new" and "closed" are cumulative
+// totals, "active" and "idle" are gauges of connections in that state now.
+func (m *ConnMetrics) Counts() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var active, idle int64
+	for _, state := range m.states {
+		switch state {
+		case http.StateActive:
+			active++
+		case http.StateIdle:
+			idle++
+		}
+	}
+	return map[string]int64{
+		"new":    m.opened,
+		"active": active,
+		"idle":   idle,
+		"closed": m.closed,
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// waitForConnCount polls until the given counter reaches want, failing the
+// test if it doesn't within a short deadline. ConnState transitions happen on
+// the server's goroutines, so the counters move asynchronously.
+func waitForConnCount(t *testing.T, m *ConnMetrics, key string, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if m.Counts()[key] == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("counter %q did not reach %d, counts: %v", key, want, m.Counts())
+}
+
+func TestConnMetricsTrackLifecycle(t *testing.T) {
+	metrics := NewConnMetrics()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.Config.ConnState = metrics.ConnState
+	srv.Start()
+	defer srv.Close()
+
+	client := srv.Client()
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// Keep-alive means the three requests share one connection, which should
+	// now be parked idle.
+	waitForConnCount(t, metrics, "idle", 1)
+	if got := metrics.Counts()["new"]; got != 1 {
+		t.Errorf("expected 1 accepted connection over keep-alive, got %d", got)
+	}
+
+	client.CloseIdleConnections()
+	waitForConnCount(t, metrics, "closed", 1)
+	waitForConnCount(t, metrics, "idle", 0)
+}
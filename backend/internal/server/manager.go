@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -12,6 +13,13 @@ import (
 	"github.com/aungh/GoLoadBalancerApplication/backend/pkg/netutils"
 )
 
+// Drainer is implemented by handlers that can refuse new work and report how
+// much work is still in flight, so shutdown can wait for it to finish.
+type Drainer interface {
+	BeginDrain()
+	ActiveConnections() int64
+}
+
 // ServerManager coordinates the startup and shutdown of both load balancer and API servers
 type ServerManager struct {
 	config      *config.Config
@@ -19,6 +27,15 @@ type ServerManager struct {
 	lbServer    *http.Server
 	apiServer   *http.Server
 	portChecker *netutils.PortChecker
+
+	drainer          Drainer       // Optional; drained before servers are closed
+	drainGracePeriod time.Duration // How long to wait for in-flight requests
+	proxyProtocol    bool          // Strip PROXY protocol headers on the LB listener
+
+	lbConnMetrics  *ConnMetrics // Connection lifecycle counters for the LB server
+	apiConnMetrics *ConnMetrics // Connection lifecycle counters for the API server
+
+	keepAlivePeriod time.Duration // TCP keep-alive probe period; 0 keeps the OS default
 }
 
 // ServerInfo represents the status of a server
@@ -45,10 +62,81 @@ func NewServerManager(cfg *config.Config, logger *zap.Logger) *ServerManager {
 	}
 }
 
-// SetServers sets the HTTP servers to be managed
+// ConfigureServerTimeouts applies the configured inbound timeouts to an
+// http.Server, leaving any unset value at its existing default.
+func ConfigureServerTimeouts(srv *http.Server, cfg config.ServerTimeoutsConfig) {
+	if cfg.IdleTimeoutSeconds > 0 {
+		srv.IdleTimeout = time.Duration(cfg.IdleTimeoutSeconds) * time.Second
+	}
+	if cfg.ReadHeaderTimeoutSeconds > 0 {
+		srv.ReadHeaderTimeout = time.Duration(cfg.ReadHeaderTimeoutSeconds) * time.Second
+	}
+	if cfg.MaxHeaderBytes > 0 {
+		srv.MaxHeaderBytes = cfg.MaxHeaderBytes
+	}
+}
+
+// SetKeepAlivePeriod sets the TCP keep-alive probe period applied to accepted
+// connections on both servers. Zero keeps the OS default.
+func (sm *ServerManager) SetKeepAlivePeriod(period time.Duration) {
+	sm.keepAlivePeriod = period
+}
+
+// SetProxyProtocol enables PROXY protocol v1/v2 parsing on the load balancer
+// listener, so RemoteAddr reflects the real client behind an L4 balancer.
+func (sm *ServerManager) SetProxyProtocol(enabled bool) {
+	sm.proxyProtocol = enabled
+}
+
+// listenAndServe starts the server, applying the keep-alive period and PROXY
+// protocol wrapping via a tuned listener when configured. PROXY parsing only
+// applies to the load balancer listener, never the management API.
+func (sm *ServerManager) listenAndServe(srv *http.Server) error {
+	wrapProxy := sm.proxyProtocol && srv == sm.lbServer
+	if sm.keepAlivePeriod <= 0 && !wrapProxy {
+		return srv.ListenAndServe()
+	}
+	lc := net.ListenConfig{KeepAlive: sm.keepAlivePeriod}
+	ln, err := lc.Listen(context.Background(), "tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	if wrapProxy {
+		ln = NewProxyProtocolListener(ln)
+	}
+	return srv.Serve(ln)
+}
+
+// SetDrainer configures shutdown draining: on Shutdown the drainer first
+// refuses new requests, then in-flight requests get up to grace to complete
+// before the servers are closed.
+func (sm *ServerManager) SetDrainer(d Drainer, grace time.Duration) {
+	sm.drainer = d
+	sm.drainGracePeriod = grace
+}
+
+// SetServers sets the HTTP servers to be managed, hooking their ConnState
+// callbacks so connection lifecycle metrics are tracked per server.
 func (sm *ServerManager) SetServers(lbServer, apiServer *http.Server) {
 	sm.lbServer = lbServer
 	sm.apiServer = apiServer
+	sm.lbConnMetrics = NewConnMetrics()
+	sm.apiConnMetrics = NewConnMetrics()
+	lbServer.ConnState = sm.lbConnMetrics.ConnState
+	apiServer.ConnState = sm.apiConnMetrics.ConnState
+}
+
+// ConnectionMetrics returns connection lifecycle counters for both servers,
+// keyed "loadBalancer" and "api". Empty before SetServers is called.
+func (sm *ServerManager) ConnectionMetrics() map[string]map[string]int64 {
+	metrics := make(map[string]map[string]int64, 2)
+	if sm.lbConnMetrics != nil {
+		metrics["loadBalancer"] = sm.lbConnMetrics.Counts()
+	}
+	if sm.apiConnMetrics != nil {
+		metrics["api"] = sm.apiConnMetrics.Counts()
+	}
+	return metrics
 }
 
 // StartServers starts both servers sequentially
@@ -115,7 +203,7 @@ func (sm *ServerManager) startLoadBalancerServer() error {
 	// Start server in a goroutine
 	errChan := make(chan error, 1)
 	go func() {
-		err := sm.lbServer.ListenAndServe()
+		err := sm.listenAndServe(sm.lbServer)
 		if err != nil && err != http.ErrServerClosed {
 			sm.logger.Error("Load Balancer server failed", zap.Error(err))
 			errChan <- err
@@ -140,7 +228,7 @@ func (sm *ServerManager) startAPIServer() error {
 	// Start server in a goroutine
 	errChan := make(chan error, 1)
 	go func() {
-		err := sm.apiServer.ListenAndServe()
+		err := sm.listenAndServe(sm.apiServer)
 		if err != nil && err != http.ErrServerClosed {
 			sm.logger.Error("API server failed", zap.Error(err), zap.Int("port", sm.config.ApiPort))
 			errChan <- err
@@ -201,12 +289,47 @@ func (sm *ServerManager) VerifyStartup() error {
 	return nil
 }
 
-// Shutdown gracefully shuts down both servers
+// Shutdown gracefully shuts down both servers. If a drainer is configured,
+// new requests are refused and in-flight proxied requests are given the grace
+// period to complete before the servers are force-closed.
 func (sm *ServerManager) Shutdown(ctx context.Context) error {
 	sm.logger.Info("Shutting down servers gracefully")
+	sm.drainBeforeShutdown(ctx)
 	return sm.shutdownBothServers(ctx)
 }
 
+// drainBeforeShutdown waits for in-flight proxied requests to finish, bounded
+// by the drain grace period and the shutdown context.
+func (sm *ServerManager) drainBeforeShutdown(ctx context.Context) {
+	if sm.drainer == nil {
+		return
+	}
+
+	sm.drainer.BeginDrain()
+	grace := sm.drainGracePeriod
+	if grace <= 0 {
+		grace = 10 * time.Second
+	}
+	sm.logger.Info("Draining in-flight requests", zap.Duration("gracePeriod", grace))
+
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		if sm.drainer.ActiveConnections() == 0 {
+			sm.logger.Info("All in-flight requests drained")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			sm.logger.Warn("Shutdown context cancelled while draining",
+				zap.Int64("remainingConnections", sm.drainer.ActiveConnections()))
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	sm.logger.Warn("Drain grace period elapsed with requests still in flight",
+		zap.Int64("remainingConnections", sm.drainer.ActiveConnections()))
+}
+
 // shutdownBothServers shuts down both servers with the given context
 func (sm *ServerManager) shutdownBothServers(ctx context.Context) error {
 	var lbErr, apiErr error
@@ -9,9 +9,13 @@ import (
 	"testing"
 	"time"
 
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/config"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/core"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
+	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
 )
 
 func TestNewServerManager(t *testing.T) {
@@ -545,3 +549,105 @@ func TestPortAvailabilityEdgeCases(t *testing.T) {
 		}
 	})
 }
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	pool := loadbalancing.NewServerPool(&loadbalancing.RoundRobinAlgorithm{})
+	srv, err := model.NewBackendServer("b1", backend.URL, 1)
+	if err != nil {
+		t.Fatalf("failed to create backend server: %v", err)
+	}
+	srv.SetStatus(model.HEALTHY)
+	pool.AddServer(srv)
+
+	lb := core.NewLoadBalancer(pool, zap.NewNop())
+
+	cfg := &config.Config{LoadBalancerPort: 8080, ApiPort: 8081}
+	sm := NewServerManager(cfg, zap.NewNop())
+	sm.SetServers(&http.Server{}, &http.Server{}) // Never started; Shutdown on them is a no-op
+	sm.SetDrainer(lb, 2*time.Second)
+
+	// Park a slow request in the proxy
+	inFlightCode := make(chan int, 1)
+	started := make(chan struct{})
+	go func() {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		close(started)
+		lb.ServeHTTP(w, req)
+		inFlightCode <- w.Code
+	}()
+	<-started
+	deadline := time.Now().Add(time.Second)
+	for lb.ActiveConnections() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if lb.ActiveConnections() == 0 {
+		t.Fatal("expected an in-flight request before shutdown")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sm.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	// The in-flight request must have completed during the grace period
+	select {
+	case code := <-inFlightCode:
+		if code != http.StatusOK {
+			t.Errorf("expected in-flight request to complete with 200, got %d", code)
+		}
+	default:
+		t.Error("expected in-flight request to complete before Shutdown returned")
+	}
+
+	// New requests during/after drain are refused
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for new requests while draining, got %d", w.Code)
+	}
+}
+
+func TestConfigureServerTimeouts(t *testing.T) {
+	srv := &http.Server{IdleTimeout: 120 * time.Second}
+
+	ConfigureServerTimeouts(srv, config.ServerTimeoutsConfig{
+		IdleTimeoutSeconds:       30,
+		ReadHeaderTimeoutSeconds: 5,
+	})
+
+	if srv.IdleTimeout != 30*time.Second {
+		t.Errorf("expected IdleTimeout 30s, got %v", srv.IdleTimeout)
+	}
+	if srv.ReadHeaderTimeout != 5*time.Second {
+		t.Errorf("expected ReadHeaderTimeout 5s, got %v", srv.ReadHeaderTimeout)
+	}
+
+	// Zero values leave existing settings untouched
+	ConfigureServerTimeouts(srv, config.ServerTimeoutsConfig{})
+	if srv.IdleTimeout != 30*time.Second || srv.ReadHeaderTimeout != 5*time.Second {
+		t.Error("expected zero-valued config to leave timeouts unchanged")
+	}
+}
+
+func TestConfigureServerTimeoutsAppliesMaxHeaderBytes(t *testing.T) {
+	srv := &http.Server{}
+
+	ConfigureServerTimeouts(srv, config.ServerTimeoutsConfig{MaxHeaderBytes: 16 << 10})
+	if srv.MaxHeaderBytes != 16<<10 {
+		t.Errorf("expected MaxHeaderBytes 16KB, got %d", srv.MaxHeaderBytes)
+	}
+
+	// Zero keeps whatever is already set (here: Go's built-in default)
+	ConfigureServerTimeouts(srv, config.ServerTimeoutsConfig{})
+	if srv.MaxHeaderBytes != 16<<10 {
+		t.Errorf("expected zero-valued config to leave MaxHeaderBytes unchanged, got %d", srv.MaxHeaderBytes)
+	}
+}
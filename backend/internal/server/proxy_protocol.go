@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// proxyV2Signature opens every PROXY protocol v2 header.
+var proxyV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// NewProxyProtocolListener wraps a listener so accepted connections strip a
+// leading PROXY protocol v1 or v2 header and report the real client address
+// via RemoteAddr. Connections without a header are passed through untouched
+// only for v1's UNKNOWN and v2's LOCAL commands; anything else not starting
+// with a recognized header is an error, since the listener is expected to sit
+// behind a PROXY-speaking balancer.
+func NewProxyProtocolListener(ln net.Listener) net.Listener {
+	return &proxyProtocolListener{Listener: ln}
+}
+
+type proxyProtocolListener struct {
+	net.Listener
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyProtocolConn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// proxyProtocolConn parses the PROXY header lazily on the first Read or
+// RemoteAddr call, so the accept loop never blocks on a slow client.
+type proxyProtocolConn struct {
+	net.Conn
+	reader   *bufio.Reader
+	once     sync.Once
+	clientIP net.Addr
+	parseErr error
+}
+
+func (c *proxyProtocolConn) parse() {
+	c.once.Do(func() {
+		c.clientIP, c.parseErr = readProxyHeader(c.reader)
+	})
+}
+
+func (c *proxyProtocolConn) Read(p []byte) (int, error) {
+	c.parse()
+	if c.parseErr != nil {
+		return 0, c.parseErr
+	}
+	return c.reader.Read(p)
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	c.parse()
+	if c.parseErr != nil || c.clientIP == nil {
+		return c.Conn.RemoteAddr()
+	}
+	return c.clientIP
+}
+
+// readProxyHeader consumes a PROXY v1 or v2 header from the reader and
+// returns the advertised client address, or nil when the header declares no
+// usable address (v1 UNKNOWN, v2 LOCAL).
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	peek, err := r.Peek(12)
+	if err != nil {
+		return nil, fmt.Errorf("reading proxy protocol header: %w", err)
+	}
+	if bytes.Equal(peek, proxyV2Signature) {
+		return readProxyV2Header(r)
+	}
+	if bytes.HasPrefix(peek, []byte("PROXY ")) {
+		return readProxyV1Header(r)
+	}
+	return nil, fmt.Errorf("connection does not start with a PROXY protocol header")
+}
+
+// readProxyV1Header parses the human-readable v1 form:
+// "PROXY TCP4 srcIP dstIP srcPort dstPort\r\n".
+func readProxyV1Header(r *bufio.Reader) (net.Addr, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading PROXY v1 line: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	fields := strings.Split(line, " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("malformed PROXY v1 header: %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	if ip == nil {
+		return nil, fmt.Errorf("invalid source IP in PROXY v1 header: %q", fields[2])
+	}
+	port, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid source port in PROXY v1 header: %q", fields[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// readProxyV2Header parses the binary v2 form.
+func readProxyV2Header(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := fullRead(r, header); err != nil {
+		return nil, fmt.Errorf("reading PROXY v2 header: %w", err)
+	}
+	versionCommand := header[12]
+	family := header[13]
+	addrLen := int(binary.BigEndian.Uint16(header[14:16]))
+	addrs := make([]byte, addrLen)
+	if _, err := fullRead(r, addrs); err != nil {
+		return nil, fmt.Errorf("reading PROXY v2 addresses: %w", err)
+	}
+	if versionCommand&0xF0 != 0x20 {
+		return nil, fmt.Errorf("unsupported PROXY v2 version byte: %#x", versionCommand)
+	}
+	if versionCommand&0x0F == 0x00 {
+		return nil, nil // LOCAL command: health probe from the balancer itself
+	}
+	switch family >> 4 {
+	case 0x1: // AF_INET
+		if addrLen < 12 {
+			return nil, fmt.Errorf("short PROXY v2 IPv4 address block")
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(addrs[0:4]),
+			Port: int(binary.BigEndian.Uint16(addrs[8:10])),
+		}, nil
+	case 0x2: // AF_INET6
+		if addrLen < 36 {
+			return nil, fmt.Errorf("short PROXY v2 IPv6 address block")
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(addrs[0:16]),
+			Port: int(binary.BigEndian.Uint16(addrs[32:34])),
+		}, nil
+	}
+	return nil, nil // AF_UNSPEC or unix sockets: keep the transport address
+}
+
+// fullRead fills buf completely from the buffered reader.
+func fullRead(r *bufio.Reader, buf []byte) (int, error) {
+	total := 0
+	for total < len(buf) {
+		n, err := r.Read(buf[total:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
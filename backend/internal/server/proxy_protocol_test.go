@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// serveOneRemoteAddr accepts connections on the wrapped listener and serves a
+// handler that echoes the observed RemoteAddr.
+func serveOneRemoteAddr(t *testing.T) (addr string, shutdown func()) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	wrapped := NewProxyProtocolListener(ln)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.RemoteAddr)
+	})}
+	go srv.Serve(wrapped)
+	return ln.Addr().String(), func() { srv.Close() }
+}
+
+func TestProxyProtocolV1RemoteAddr(t *testing.T) {
+	addr, shutdown := serveOneRemoteAddr(t)
+	defer shutdown()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+	fmt.Fprintf(conn, "PROXY TCP4 203.0.113.7 10.0.0.1 54321 80\r\n")
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: example.test\r\nConnection: close\r\n\r\n")
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	defer resp.Body.Close()
+	buf := make([]byte, 256)
+	n, _ := resp.Body.Read(buf)
+	got := string(buf[:n])
+	if got != "203.0.113.7:54321" {
+		t.Errorf("expected RemoteAddr 203.0.113.7:54321 from the PROXY header, got %q", got)
+	}
+}
+
+func TestProxyProtocolV2RemoteAddr(t *testing.T) {
+	addr, shutdown := serveOneRemoteAddr(t)
+	defer shutdown()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+	// v2 header: PROXY command, TCP over IPv4, 12 address bytes
+	header := append([]byte{}, proxyV2Signature...)
+	header = append(header, 0x21, 0x11, 0x00, 0x0C)
+	header = append(header, 198, 51, 100, 9) // source IP
+	header = append(header, 10, 0, 0, 1)     // destination IP
+	header = append(header, 0xD4, 0x31)      // source port 54321
+	header = append(header, 0x00, 0x50)      // destination port 80
+	if _, err := conn.Write(header); err != nil {
+		t.Fatalf("failed to write v2 header: %v", err)
+	}
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: example.test\r\nConnection: close\r\n\r\n")
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	defer resp.Body.Close()
+	buf := make([]byte, 256)
+	n, _ := resp.Body.Read(buf)
+	got := string(buf[:n])
+	if got != "198.51.100.9:54321" {
+		t.Errorf("expected RemoteAddr 198.51.100.9:54321 from the PROXY v2 header, got %q", got)
+	}
+}
+
+func TestProxyProtocolRejectsBareConnections(t *testing.T) {
+	addr, shutdown := serveOneRemoteAddr(t)
+	defer shutdown()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+	// The read error surfaces as either a closed connection or a generic 400
+	// from the HTTP server; either way the request must not be served.
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: example.test\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Error("expected the connection without a PROXY header to be rejected")
+		}
+	}
+}
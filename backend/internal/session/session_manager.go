@@ -1,38 +1,199 @@
 package session
 
 import (
+	"hash/fnv"
+	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 
+	"go.uber.org/zap"
+
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
 )
 
 const stickyCookieName = "LB_STICKY_SERVER"
 
-// SessionManager manages sticky sessions via cookies.
-type SessionManager struct{}
+// maxStickyValueLen caps accepted cookie values well above any real server
+// ID; anything longer is junk from another app or a tampered client.
+const maxStickyValueLen = 128
+
+// SessionManager manages sticky sessions via cookies. With rebalancing
+// enabled it also tracks when each backend first appeared in the pool, so a
+// fraction of pinned sessions can be re-spread onto newly added backends.
+type SessionManager struct {
+	mu                sync.Mutex
+	rebalanceFraction float64              // Chance a sticky hit is re-pinned while a new backend exists; 0 disables
+	rebalanceWindow   time.Duration        // How long after being added a backend counts as new
+	firstSeen         map[string]time.Time // Backend ID -> when it was first observed in the pool
+	primed            bool                 // Whether the initial pool membership has been recorded
+	rng               *rand.Rand
+	sessionCounts     map[string]uint64 // Backend ID -> sticky sessions pinned to it (approximate)
+	logger            *zap.Logger
+	queryParam        string // Query parameter hashed to a backend; empty disables the strategy
+}
 
 func NewSessionManager() *SessionManager {
-	return &SessionManager{}
+	return &SessionManager{
+		firstSeen:     make(map[string]time.Time),
+		rng:           rand.New(rand.NewSource(time.Now().UnixNano())),
+		sessionCounts: make(map[string]uint64),
+		logger:        zap.NewNop(),
+	}
+}
+
+// SetLogger attaches a logger for debug-level diagnostics such as rejected
+// cookie values.
+func (sm *SessionManager) SetLogger(logger *zap.Logger) {
+	if logger != nil {
+		sm.logger = logger
+	}
+}
+
+// validStickyValue reports whether a cookie value is shaped like a server ID:
+// bounded length and limited to the characters IDs are built from. Values
+// from other apps or tampered clients fail this and fall through to fresh
+// selection.
+func validStickyValue(value string) bool {
+	if len(value) == 0 || len(value) > maxStickyValueLen {
+		return false
+	}
+	for _, c := range value {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// EnableRebalancing turns on gradual sticky-session rebalancing: while a
+// backend added within the window is present, each sticky hit has the given
+// probability of being re-pinned through fresh selection, so new capacity
+// ramps up instead of starving until sessions expire.
+func (sm *SessionManager) EnableRebalancing(fraction float64, window time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	sm.rebalanceFraction = fraction
+	sm.rebalanceWindow = window
+}
+
+// SetQueryParamStrategy enables query-string affinity: when the named
+// parameter is present, its value is hashed to a healthy backend instead of
+// consulting the sticky cookie. Useful for clients that carry a session token
+// in the URL rather than cookies or headers.
+func (sm *SessionManager) SetQueryParamStrategy(param string) {
+	sm.queryParam = param
+}
+
+// queryStickyServer maps the configured query parameter's value onto a
+// healthy backend by hash, nil when the parameter is absent or no backend is
+// healthy.
+func (sm *SessionManager) queryStickyServer(req *http.Request, pool *loadbalancing.ServerPool) *model.BackendServer {
+	value := req.URL.Query().Get(sm.queryParam)
+	if value == "" {
+		return nil
+	}
+	healthy := pool.GetHealthyServers()
+	if len(healthy) == 0 {
+		return nil
+	}
+	h := fnv.New32a()
+	h.Write([]byte(value))
+	return healthy[h.Sum32()%uint32(len(healthy))]
 }
 
 // GetStickyServer returns the backend server for the sticky session if valid and healthy, else nil.
 func (sm *SessionManager) GetStickyServer(req *http.Request, pool *loadbalancing.ServerPool) *model.BackendServer {
+	if sm.queryParam != "" {
+		if server := sm.queryStickyServer(req, pool); server != nil {
+			return server
+		}
+	}
 	cookie, err := req.Cookie(stickyCookieName)
 	if err != nil || cookie.Value == "" {
 		return nil
 	}
+	if !validStickyValue(cookie.Value) {
+		sm.logger.Debug("Ignoring malformed sticky session cookie",
+			zap.Int("valueLength", len(cookie.Value)))
+		return nil
+	}
 	for _, server := range pool.GetHealthyServers() {
 		if server.ID == cookie.Value {
+			if sm.shouldRebalance(pool, server.ID) {
+				// Break the pin: the caller falls through to fresh selection
+				// and sets a new sticky cookie.
+				return nil
+			}
 			return server
 		}
 	}
+	// The cookie points at a server that's gone or unhealthy; fall through to
+	// fresh selection, which re-pins the session.
+	sm.logger.Debug("Sticky session cookie references unknown or unhealthy server",
+		zap.String("serverId", cookie.Value))
 	return nil
 }
 
+// shouldRebalance records first-seen times for the pool's backends and rolls
+// the rebalance dice when a backend other than the pinned one was added
+// within the window. The first observation seeds the existing pool as old, so
+// startup membership never triggers rebalancing.
+func (sm *SessionManager) shouldRebalance(pool *loadbalancing.ServerPool, pinnedID string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.rebalanceFraction <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	newBackendPresent := false
+	for _, server := range pool.GetHealthyServers() {
+		seen, known := sm.firstSeen[server.ID]
+		if !known {
+			seen = now
+			if !sm.primed {
+				seen = time.Time{} // Initial membership counts as old
+			}
+			sm.firstSeen[server.ID] = seen
+		}
+		if server.ID != pinnedID && !seen.IsZero() && now.Sub(seen) < sm.rebalanceWindow {
+			newBackendPresent = true
+		}
+	}
+	sm.primed = true
+
+	return newBackendPresent && sm.rng.Float64() < sm.rebalanceFraction
+}
+
+// SessionCounts returns how many sticky sessions have been pinned to each
+// backend. Counts are approximate: they grow on every pin and are never
+// decremented when cookies expire, which is enough to see affinity skew.
+func (sm *SessionManager) SessionCounts() map[string]uint64 {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	counts := make(map[string]uint64, len(sm.sessionCounts))
+	for id, n := range sm.sessionCounts {
+		counts[id] = n
+	}
+	return counts
+}
+
 // SetStickyServer sets a cookie for the chosen backend server.
 func (sm *SessionManager) SetStickyServer(w http.ResponseWriter, server *model.BackendServer) {
+	sm.mu.Lock()
+	sm.sessionCounts[server.ID]++
+	sm.mu.Unlock()
 	http.SetCookie(w, &http.Cookie{
 		Name:     stickyCookieName,
 		Value:    server.ID,
@@ -3,7 +3,9 @@ package session
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/loadbalancing"
 	"github.com/aungh/GoLoadBalancerApplication/backend/internal/model"
@@ -78,3 +80,167 @@ func TestGetStickyServer_NoCookie(t *testing.T) {
 		t.Errorf("expected nil when no cookie, got %v", got)
 	}
 }
+
+func TestRebalancingRepinsFractionOfStickyHits(t *testing.T) {
+	sm := NewSessionManager()
+	sm.EnableRebalancing(0.5, time.Minute)
+
+	s1 := &model.BackendServer{ID: "s1", HealthStatus: model.HEALTHY}
+	s2 := &model.BackendServer{ID: "s2", HealthStatus: model.HEALTHY}
+	pool := newTestPoolWithServers([]*model.BackendServer{s1, s2})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: stickyCookieName, Value: "s1"})
+
+	// Prime the manager with the initial membership: established backends
+	// never trigger rebalancing.
+	for i := 0; i < 50; i++ {
+		if got := sm.GetStickyServer(req, pool); got == nil || got.ID != "s1" {
+			t.Fatalf("expected stable pin with no new backends, got %v", got)
+		}
+	}
+
+	// A newly added backend starts pulling a share of sticky traffic
+	pool.AddServer(&model.BackendServer{ID: "s3", HealthStatus: model.HEALTHY})
+	repinned := 0
+	const hits = 400
+	for i := 0; i < hits; i++ {
+		if sm.GetStickyServer(req, pool) == nil {
+			repinned++
+		}
+	}
+	// With fraction 0.5 the expected count is ~200; allow generous slack
+	if repinned < hits/10 || repinned > hits*9/10 {
+		t.Errorf("expected roughly half of sticky hits re-pinned, got %d of %d", repinned, hits)
+	}
+}
+
+func TestRebalancingDisabledKeepsPinsStable(t *testing.T) {
+	sm := NewSessionManager()
+
+	s1 := &model.BackendServer{ID: "s1", HealthStatus: model.HEALTHY}
+	pool := newTestPoolWithServers([]*model.BackendServer{s1})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: stickyCookieName, Value: "s1"})
+	sm.GetStickyServer(req, pool)
+
+	pool.AddServer(&model.BackendServer{ID: "s2", HealthStatus: model.HEALTHY})
+	for i := 0; i < 100; i++ {
+		if got := sm.GetStickyServer(req, pool); got == nil || got.ID != "s1" {
+			t.Errorf("expected pin to hold with rebalancing disabled, got %v", got)
+		}
+	}
+}
+
+func TestRebalancingWindowExpires(t *testing.T) {
+	sm := NewSessionManager()
+	sm.EnableRebalancing(1.0, 10*time.Millisecond)
+
+	s1 := &model.BackendServer{ID: "s1", HealthStatus: model.HEALTHY}
+	pool := newTestPoolWithServers([]*model.BackendServer{s1})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: stickyCookieName, Value: "s1"})
+	sm.GetStickyServer(req, pool)
+
+	pool.AddServer(&model.BackendServer{ID: "s2", HealthStatus: model.HEALTHY})
+	if got := sm.GetStickyServer(req, pool); got != nil {
+		t.Errorf("expected re-pin while the new backend is inside the window, got %v", got)
+	}
+
+	// Once the window passes the pool settles back to stable pins
+	time.Sleep(20 * time.Millisecond)
+	if got := sm.GetStickyServer(req, pool); got == nil || got.ID != "s1" {
+		t.Errorf("expected stable pin after the window expired, got %v", got)
+	}
+}
+
+func TestGetStickyServer_MalformedCookieValue(t *testing.T) {
+	sm := NewSessionManager()
+	srv := &model.BackendServer{ID: "s1", HealthStatus: model.HEALTHY}
+	pool := newTestPoolWithServers([]*model.BackendServer{srv})
+
+	for _, value := range []string{"s1; DROP", "<script>", "id with spaces", "\x7fbinary"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: stickyCookieName, Value: value})
+		if got := sm.GetStickyServer(req, pool); got != nil {
+			t.Errorf("expected malformed cookie %q to be ignored, got %v", value, got)
+		}
+	}
+}
+
+func TestGetStickyServer_OversizedCookieValue(t *testing.T) {
+	sm := NewSessionManager()
+	srv := &model.BackendServer{ID: "s1", HealthStatus: model.HEALTHY}
+	pool := newTestPoolWithServers([]*model.BackendServer{srv})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: stickyCookieName, Value: strings.Repeat("a", maxStickyValueLen+1)})
+	if got := sm.GetStickyServer(req, pool); got != nil {
+		t.Errorf("expected oversized cookie to be ignored, got %v", got)
+	}
+}
+
+func TestGetStickyServer_StaleReferenceTriggersReselection(t *testing.T) {
+	sm := NewSessionManager()
+	srv := &model.BackendServer{ID: "s1", HealthStatus: model.HEALTHY}
+	pool := newTestPoolWithServers([]*model.BackendServer{srv})
+
+	// Well-formed cookie naming a server that no longer exists
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: stickyCookieName, Value: "long-gone"})
+	if got := sm.GetStickyServer(req, pool); got != nil {
+		t.Errorf("expected stale cookie to fall through to fresh selection, got %v", got)
+	}
+}
+
+func TestQueryParamStickyRoutesByTokenHash(t *testing.T) {
+	sm := NewSessionManager()
+	sm.SetQueryParamStrategy("session_id")
+
+	servers := []*model.BackendServer{
+		{ID: "s1", HealthStatus: model.HEALTHY},
+		{ID: "s2", HealthStatus: model.HEALTHY},
+		{ID: "s3", HealthStatus: model.HEALTHY},
+	}
+	pool := newTestPoolWithServers(servers)
+
+	// The same token always lands on the same backend
+	req := httptest.NewRequest("GET", "/?session_id=abc123", nil)
+	first := sm.GetStickyServer(req, pool)
+	if first == nil {
+		t.Fatal("expected a backend for a present session token")
+	}
+	for i := 0; i < 10; i++ {
+		if got := sm.GetStickyServer(req, pool); got != first {
+			t.Fatalf("expected stable backend for the same token, got %v then %v", first, got)
+		}
+	}
+
+	// The query strategy takes precedence over a conflicting cookie
+	req.AddCookie(&http.Cookie{Name: stickyCookieName, Value: "s1"})
+	if got := sm.GetStickyServer(req, pool); got != first {
+		t.Errorf("expected the query token to win over the cookie, got %v", got)
+	}
+}
+
+func TestQueryParamStickyAbsentFallsThrough(t *testing.T) {
+	sm := NewSessionManager()
+	sm.SetQueryParamStrategy("session_id")
+
+	srv := &model.BackendServer{ID: "s1", HealthStatus: model.HEALTHY}
+	pool := newTestPoolWithServers([]*model.BackendServer{srv})
+
+	// No token and no cookie: fresh selection
+	req := httptest.NewRequest("GET", "/", nil)
+	if got := sm.GetStickyServer(req, pool); got != nil {
+		t.Errorf("expected nil with no token or cookie, got %v", got)
+	}
+
+	// No token but a valid cookie: the cookie strategy still applies
+	req.AddCookie(&http.Cookie{Name: stickyCookieName, Value: "s1"})
+	if got := sm.GetStickyServer(req, pool); got == nil || got.ID != "s1" {
+		t.Errorf("expected cookie affinity without a token, got %v", got)
+	}
+}
@@ -20,6 +20,7 @@ type DiagnosticInfo struct {
 	Network       NetworkInfo       `json:"network"`
 	Services      ServicesInfo      `json:"services"`
 	Configuration ConfigurationInfo `json:"configuration"`
+	Backends      []BackendHealth   `json:"backends,omitempty"`
 	Errors        []DiagnosticError `json:"errors,omitempty"`
 	Suggestions   []string          `json:"suggestions,omitempty"`
 }
@@ -46,6 +47,14 @@ type PortInfo struct {
 	Error     string `json:"error,omitempty"`
 }
 
+// BackendHealth summarizes one backend's health for the diagnostic report
+type BackendHealth struct {
+	ID             string `json:"id"`
+	URL            string `json:"url"`
+	Status         string `json:"status"`
+	ResponseTimeMs int64  `json:"responseTimeMs"`
+}
+
 // ServicesInfo contains information about running services
 type ServicesInfo struct {
 	LoadBalancer ServiceStatus `json:"loadBalancer"`
@@ -237,6 +246,29 @@ func (d *Diagnostics) checkServiceStatus(port int, path string) ServiceStatus {
 	return status
 }
 
+// ProbeBackend checks a single backend directly over HTTP and returns its
+// health summary. Used by the CLI, where no running pool is available.
+func (d *Diagnostics) ProbeBackend(id, rawURL string) BackendHealth {
+	health := BackendHealth{
+		ID:     id,
+		URL:    rawURL,
+		Status: "UNHEALTHY",
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	start := time.Now()
+	resp, err := client.Get(rawURL)
+	health.ResponseTimeMs = time.Since(start).Milliseconds()
+	if err != nil {
+		return health
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 400 {
+		health.Status = "HEALTHY"
+	}
+	return health
+}
+
 // checkConfiguration checks configuration files and binaries
 func (d *Diagnostics) checkConfiguration(configPath, binaryPath string) ConfigurationInfo {
 	config := ConfigurationInfo{
@@ -462,6 +494,20 @@ func (d *Diagnostics) GenerateReport(info *DiagnosticInfo) string {
 	report.WriteString(fmt.Sprintf("  Binary File: %s (%s)\n", binaryStatus, info.Configuration.BinaryFile.Path))
 	report.WriteString("\n")
 
+	// Backend Health
+	if len(info.Backends) > 0 {
+		report.WriteString("Backend Health:\n")
+		for _, backend := range info.Backends {
+			status := "❌ " + backend.Status
+			if backend.Status == "HEALTHY" || backend.Status == "DEGRADED" {
+				status = "✅ " + backend.Status
+			}
+			report.WriteString(fmt.Sprintf("  %s: %s (%s, last response %dms)\n",
+				backend.ID, status, backend.URL, backend.ResponseTimeMs))
+		}
+		report.WriteString("\n")
+	}
+
 	// Errors and Issues
 	if len(info.Errors) > 0 {
 		report.WriteString("Issues Found:\n")
@@ -0,0 +1,56 @@
+package diagnostics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestGenerateReportIncludesBackendHealth(t *testing.T) {
+	d := NewDiagnostics(zap.NewNop())
+
+	info := &DiagnosticInfo{
+		Timestamp: time.Now(),
+		Backends: []BackendHealth{
+			{ID: "web-1", URL: "http://localhost:9001", Status: "HEALTHY", ResponseTimeMs: 12},
+			{ID: "web-2", URL: "http://localhost:9002", Status: "UNHEALTHY", ResponseTimeMs: 5000},
+		},
+	}
+
+	report := d.GenerateReport(info)
+	if !strings.Contains(report, "Backend Health:") {
+		t.Error("expected report to contain a Backend Health section")
+	}
+	if !strings.Contains(report, "web-1") || !strings.Contains(report, "HEALTHY") {
+		t.Error("expected report to list the healthy backend")
+	}
+	if !strings.Contains(report, "web-2") || !strings.Contains(report, "UNHEALTHY") {
+		t.Error("expected report to list the unhealthy backend")
+	}
+	if !strings.Contains(report, "12ms") {
+		t.Error("expected report to include backend response times")
+	}
+}
+
+func TestProbeBackend(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	d := NewDiagnostics(zap.NewNop())
+
+	health := d.ProbeBackend("up", backend.URL)
+	if health.Status != "HEALTHY" {
+		t.Errorf("expected HEALTHY for a responding backend, got %s", health.Status)
+	}
+
+	health = d.ProbeBackend("down", "http://127.0.0.1:1")
+	if health.Status != "UNHEALTHY" {
+		t.Errorf("expected UNHEALTHY for an unreachable backend, got %s", health.Status)
+	}
+}
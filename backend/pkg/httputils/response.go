@@ -1,6 +1,7 @@
 package httputils
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -27,6 +28,37 @@ type SuccessResponse struct {
 	RequestID string      `json:"requestId,omitempty"`
 }
 
+// requestIDContextKey keys the request ID on a context; the unexported type
+// keeps it collision-free with other packages' context values.
+type requestIDContextKey struct{}
+
+// WithRequestID returns a context carrying the request ID. The request ID
+// middleware calls this for every inbound request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored on the context, or the
+// empty string when no request ID middleware ran.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// RespondData writes data wrapped in the standard success envelope with the
+// given status code. Every successful API response shares the
+// SuccessResponse shape:
+//
+//	{"data": <payload>, "message": "...", "timestamp": "..."}
+//
+// so clients can parse all 2xx responses uniformly.
+func RespondData(w http.ResponseWriter, status int, data interface{}) {
+	RespondJSON(w, status, SuccessResponse{
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+}
+
 // RespondJSON writes the given payload as JSON with the specified status code.
 func RespondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -84,6 +116,50 @@ func RespondCreated(w http.ResponseWriter, data interface{}, message string) {
 	RespondJSON(w, http.StatusCreated, successResp)
 }
 
+// RespondErrorCtx is RespondError with the context's request ID included in
+// the response body.
+func RespondErrorCtx(ctx context.Context, w http.ResponseWriter, status int, err error) {
+	RespondJSON(w, status, ErrorResponse{
+		Error:      err.Error(),
+		Message:    getErrorMessage(status),
+		StatusCode: status,
+		Timestamp:  time.Now(),
+		RequestID:  RequestIDFromContext(ctx),
+	})
+}
+
+// RespondSuccessCtx is RespondSuccess with the context's request ID included
+// in the response body.
+func RespondSuccessCtx(ctx context.Context, w http.ResponseWriter, data interface{}, message string) {
+	RespondJSON(w, http.StatusOK, SuccessResponse{
+		Data:      data,
+		Message:   message,
+		Timestamp: time.Now(),
+		RequestID: RequestIDFromContext(ctx),
+	})
+}
+
+// RespondCreatedCtx is RespondCreated with the context's request ID included
+// in the response body.
+func RespondCreatedCtx(ctx context.Context, w http.ResponseWriter, data interface{}, message string) {
+	RespondJSON(w, http.StatusCreated, SuccessResponse{
+		Data:      data,
+		Message:   message,
+		Timestamp: time.Now(),
+		RequestID: RequestIDFromContext(ctx),
+	})
+}
+
+// RespondDataCtx is RespondData with the context's request ID included in the
+// response body.
+func RespondDataCtx(ctx context.Context, w http.ResponseWriter, status int, data interface{}) {
+	RespondJSON(w, status, SuccessResponse{
+		Data:      data,
+		Timestamp: time.Now(),
+		RequestID: RequestIDFromContext(ctx),
+	})
+}
+
 // LogAndRespondError logs the error and sends a structured error response
 func LogAndRespondError(w http.ResponseWriter, logger *zap.Logger, status int, err error, context string) {
 	logger.Error(context,
@@ -1,6 +1,7 @@
 package httputils
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -66,3 +67,29 @@ func TestRespondJSONWithNilPayload(t *testing.T) {
 		t.Errorf("Expected empty body, got %s", w.Body.String())
 	}
 }
+
+func TestRequestIDIncludedInResponses(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-123")
+
+	w := httptest.NewRecorder()
+	RespondSuccessCtx(ctx, w, map[string]string{"ok": "yes"}, "done")
+	var success SuccessResponse
+	if err := json.NewDecoder(w.Body).Decode(&success); err != nil {
+		t.Fatalf("failed to decode success response: %v", err)
+	}
+	if success.RequestID != "req-123" {
+		t.Errorf("expected request ID req-123 in success response, got %q", success.RequestID)
+	}
+
+	w = httptest.NewRecorder()
+	RespondErrorCtx(ctx, w, http.StatusBadRequest, errors.New("bad input"))
+	var failure struct {
+		RequestID string `json:"requestId"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&failure); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	if failure.RequestID != "req-123" {
+		t.Errorf("expected request ID req-123 in error response, got %q", failure.RequestID)
+	}
+}
@@ -1,24 +1,64 @@
 package logger
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
+// FileConfig describes rotating file output for the logger.
+type FileConfig struct {
+	Path       string // Log file path; empty disables file output
+	MaxSizeMB  int    // Rotate after the file reaches this size
+	MaxBackups int    // Number of rotated files to keep
+	MaxAgeDays int    // Delete rotated files older than this
+}
+
 var log *zap.Logger
 
+// atomicLevel backs the running logger so the level can be changed at runtime.
+var atomicLevel = zap.NewAtomicLevel()
+
+// InitLogger initializes the global logger. The debug flag picks the defaults
+// (debug/console vs info/json); LOG_LEVEL and LOG_FORMAT env vars override them.
 func InitLogger(debug bool) {
-	config := zap.NewProductionConfig()
+	level, format := "info", "json"
 	if debug {
+		level, format = "debug", "console"
+	}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		level = v
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		format = v
+	}
+	InitLoggerWithOptions(level, format)
+}
+
+// InitLoggerWithOptions initializes the global logger with an explicit level
+// ("debug", "info", "warn", "error") and format ("json" or "console").
+func InitLoggerWithOptions(level, format string) {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		parsed = zapcore.InfoLevel
+	}
+	atomicLevel.SetLevel(parsed)
+
+	config := zap.NewProductionConfig()
+	if format == "console" {
 		config = zap.NewDevelopmentConfig()
 		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	}
+	config.Level = atomicLevel
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	config.OutputPaths = []string{"stdout"}
 	config.ErrorOutputPaths = []string{"stderr"}
 
-	var err error
 	log, err = config.Build()
 	if err != nil {
 		panic(err)
@@ -26,6 +66,68 @@ func InitLogger(debug bool) {
 	zap.ReplaceGlobals(log)
 }
 
+// InitLoggerWithFile initializes the global logger writing to stdout and,
+// when file.Path is set, to a size/age-rotated file as well.
+func InitLoggerWithFile(level, format string, file FileConfig) {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		parsed = zapcore.InfoLevel
+	}
+	atomicLevel.SetLevel(parsed)
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	var encoder zapcore.Encoder
+	if format == "console" {
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	} else {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+
+	cores := []zapcore.Core{
+		zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), atomicLevel),
+	}
+	if file.Path != "" {
+		rotator := &lumberjack.Logger{
+			Filename:   file.Path,
+			MaxSize:    file.MaxSizeMB,
+			MaxBackups: file.MaxBackups,
+			MaxAge:     file.MaxAgeDays,
+		}
+		cores = append(cores, zapcore.NewCore(encoder, zapcore.AddSync(rotator), atomicLevel))
+	}
+
+	log = zap.New(zapcore.NewTee(cores...), zap.AddCaller())
+	zap.ReplaceGlobals(log)
+}
+
+// ParseLevel parses a textual log level into a zap level.
+func ParseLevel(level string) (zapcore.Level, error) {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+		return l, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	return l, nil
+}
+
+// SetLevel changes the level of the running logger without a restart.
+func SetLevel(level string) error {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	atomicLevel.SetLevel(parsed)
+	return nil
+}
+
+// GetLevel returns the current level of the running logger.
+func GetLevel() string {
+	return atomicLevel.Level().String()
+}
+
 func Info(msg string, fields ...zap.Field) {
 	zap.L().Info(msg, fields...)
 }
@@ -1,9 +1,13 @@
 package logger
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
 )
 
@@ -20,3 +24,67 @@ func TestLoggerInfoAndError(t *testing.T) {
 		t.Error("expected at least one log entry")
 	}
 }
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]zapcore.Level{
+		"debug": zapcore.DebugLevel,
+		"INFO":  zapcore.InfoLevel,
+		"warn":  zapcore.WarnLevel,
+		"error": zapcore.ErrorLevel,
+	}
+	for input, want := range cases {
+		got, err := ParseLevel(input)
+		if err != nil {
+			t.Errorf("ParseLevel(%q) returned error: %v", input, err)
+		}
+		if got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", input, got, want)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestSetLevelAtRuntime(t *testing.T) {
+	InitLoggerWithOptions("info", "json")
+
+	if zap.L().Core().Enabled(zapcore.DebugLevel) {
+		t.Error("expected debug to be disabled at info level")
+	}
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	if !zap.L().Core().Enabled(zapcore.DebugLevel) {
+		t.Error("expected debug to be enabled after SetLevel")
+	}
+
+	if err := SetLevel("nope"); err == nil {
+		t.Error("expected error for invalid level")
+	}
+}
+
+func TestInitLoggerWithFileWritesEntries(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "lb.log")
+	InitLoggerWithFile("info", "json", FileConfig{
+		Path:       logPath,
+		MaxSizeMB:  1,
+		MaxBackups: 1,
+		MaxAgeDays: 1,
+	})
+
+	Info("first file entry")
+	Info("second file entry")
+	Sync()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("expected log file to be created: %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "first file entry") || !strings.Contains(content, "second file entry") {
+		t.Errorf("expected log entries in file, got: %s", content)
+	}
+}
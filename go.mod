@@ -6,6 +6,7 @@ require (
 	github.com/gorilla/mux v1.8.1
 	go.uber.org/zap v1.11.0
 	golang.org/x/time v0.5.0
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 	gopkg.in/yaml.v3 v3.0.1
 )
 